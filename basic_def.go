@@ -1,27 +1,47 @@
 package zerror
 
+import "sync"
+
 type defMapT map[string]*Def
 
 var (
 	defMap = defMapT{}
+	// def code -> group name
+	defGroups = map[string]string{}
+	// guards defMap and defGroups against registering while reading them concurrently
+	defMu sync.RWMutex
 )
 
 func init() {
 	defMap.init()
 }
 
+// the registered def of code
+func lookupDef(code string) (*Def, bool) {
+	defMu.RLock()
+	defer defMu.RUnlock()
+	def, ok := defMap[code]
+	return def, ok
+}
+
+// group name of built-in defs
+const builtinGroup = `zerror`
+
 const (
-	CodeInternal        = `zerror:internal`
-	codeBadRequest      = `zerror:bad_request`
-	codeForbidden       = `zerror:forbidden`
-	codeNofFound        = `zerror:not_found`
-	codeUnauthenticated = `zerror:unauthenticated`
-	codeAlreadyExists   = `zerror:already_exists`
+	CodeInternal         = `zerror:internal`
+	codeBadRequest       = `zerror:bad_request`
+	codeForbidden        = `zerror:forbidden`
+	codeNofFound         = `zerror:not_found`
+	codeUnauthenticated  = `zerror:unauthenticated`
+	codeAlreadyExists    = `zerror:already_exists`
+	codeDeadlineExceeded = `zerror:deadline_exceeded`
+	codeCanceled         = `zerror:canceled`
 )
 
 var Internal = &Def{
 	Code:        CodeInternal,
 	Status:      StatusInternal,
+	Severity:    SeverityError,
 	Msg:         `internal error`,
 	Description: `server internal error`,
 }
@@ -61,14 +81,37 @@ var AlreadyExists = &Def{
 	Description: `already exists`,
 }
 
+var DeadlineExceeded = &Def{
+	Code:        codeDeadlineExceeded,
+	Msg:         `deadline exceeded`,
+	Status:      StatusDeadlineExceeded,
+	Retry:       Retryable,
+	Description: `operation timed out`,
+}
+
+var Canceled = &Def{
+	Code:        codeCanceled,
+	Msg:         `canceled`,
+	Status:      StatusCancelled,
+	Description: `operation canceled`,
+}
+
 func (m *defMapT) init() {
 	inited := defMapT{
-		CodeInternal:        Internal,
-		codeBadRequest:      BadRequest,
-		codeForbidden:       Forbidden,
-		codeNofFound:        NotFound,
-		codeUnauthenticated: Unauthenticated,
-		codeAlreadyExists:   AlreadyExists,
+		CodeInternal:         Internal,
+		codeBadRequest:       BadRequest,
+		codeForbidden:        Forbidden,
+		codeNofFound:         NotFound,
+		codeUnauthenticated:  Unauthenticated,
+		codeAlreadyExists:    AlreadyExists,
+		codeDeadlineExceeded: DeadlineExceeded,
+		codeCanceled:         Canceled,
 	}
+	defMu.Lock()
+	defer defMu.Unlock()
 	*m = inited
+	defGroups = make(map[string]string, len(inited))
+	for code := range inited {
+		defGroups[code] = builtinGroup
+	}
 }
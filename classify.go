@@ -0,0 +1,66 @@
+package zerror
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"os"
+)
+
+// returns the def to wrap err with, and whether err is classified
+type Classifier func(err error) (*Def, bool)
+
+// classify errors matching target with errors.Is as def
+func ClassifyIs(target error, def *Def) Classifier {
+	return func(err error) (*Def, bool) {
+		return def, errors.Is(err, target)
+	}
+}
+
+// classify errors that match returns true as def
+func ClassifyFunc(match func(err error) bool, def *Def) Classifier {
+	return func(err error) (*Def, bool) {
+		return def, match(err)
+	}
+}
+
+// applied after the classifiers set by WithClassifiers
+var defaultClassifiers = []Classifier{
+	ClassifyIs(context.DeadlineExceeded, DeadlineExceeded),
+	ClassifyIs(context.Canceled, Canceled),
+	ClassifyIs(os.ErrNotExist, NotFound),
+	ClassifyIs(sql.ErrNoRows, NotFound),
+	ClassifyFunc(isNetTimeout, DeadlineExceeded),
+}
+
+func isNetTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// convert err to *Error:
+// if err is or wraps an error generated by zerror, the outermost one is returned,
+// if err is a def, a new error generated by the def is returned,
+// else err is wrapped with the def of the first matched classifier, or Internal if none matched.
+// returns nil if err is nil
+func From(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	zerr := &Error{}
+	if ok := errors.As(err, &zerr); ok {
+		return zerr
+	}
+	if def, ok := err.(*Def); ok {
+		return Manager.runCreateHooks(def.wrapf(nil, 3, ``))
+	}
+	for _, classifiers := range [][]Classifier{Manager.classifiers, defaultClassifiers} {
+		for _, classify := range classifiers {
+			if def, ok := classify(err); ok {
+				return Manager.runWrapHooks(def.wrapf(err, 3, ``))
+			}
+		}
+	}
+	return Manager.runWrapHooks(Internal.wrapf(err, 3, ``))
+}
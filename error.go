@@ -7,7 +7,8 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type Def struct {
@@ -17,67 +18,141 @@ type Def struct {
 	Description string `json:"desc"`
 	// can be used as http status code or grpc status code
 	Status Status `json:"status"`
+	// used by loggers, if not set, it's decided by status, see StatusSeverity
+	Severity Severity `json:"severity,omitempty"`
+	// whether errors generated by this def can be retried
+	Retry Retryability `json:"retry,omitempty"`
+	// the minimal duration to wait before retrying, implies retryable
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+	// errors generated by this def are also caused by the parent,
+	// see Derive for what is inherited
+	Parent *Def `json:"-"`
 
 	// extended fields
 	extensions map[string]interface{}
+	// unregistered def of a code received from remote services, see FromRemote
+	remote bool
 }
 
 type Data map[string]interface{}
 
+// context of one layer of the error chain,
+// Data only holds data added to this layer, use AllData to get data of the whole chain,
+// Data is nil if no data is added, use WithData instead of assigning to it
 type ZContext struct {
+	// program counter of the caller, resolved lazily when caller is read,
+	// if it's 0, callerLoc and callerName are used, like errors decoded from json
+	callerPC   uintptr
 	callerLoc  string
 	callerName string
 	Data       Data
 	Ctx        context.Context
 }
 
+// Deprecated: errors are immutable and data is kept per layer, use WithData and AllData
 func (ctx *ZContext) Merge(m *ZContext) {
-	ctx.callerLoc = m.callerLoc
-	ctx.callerName += `/` + m.callerName
+	_, name := ctx.caller(false)
+	ctx.callerLoc, ctx.callerName = m.caller(true)
+	ctx.callerName = name + `/` + ctx.callerName
+	ctx.callerPC = 0
+	if ctx.Data == nil && len(m.Data) > 0 {
+		ctx.Data = make(Data, len(m.Data))
+	}
 	for k, v := range m.Data {
 		ctx.Data[k] = v
 	}
 }
 
+func (ctx *ZContext) caller(withLoc bool) (loc string, name string) {
+	if ctx.callerPC == 0 {
+		return ctx.callerLoc, ctx.callerName
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{ctx.callerPC}).Next()
+	if withLoc {
+		loc = frame.File + "/" + strconv.Itoa(frame.Line)
+	}
+	name = frame.Function[strings.LastIndexByte(frame.Function, '.')+1:]
+	return
+}
+
+// caller of this layer, the location is resolved only in debug mode or for Internal, like GetCaller
+func (ze *Error) layerCaller() (string, string) {
+	return ze.caller(Manager.debugMode || ze.Def.Code == CodeInternal)
+}
+
 type Error struct {
 	cause error
 	*Def
 	msg string
 	ZContext
+	stack StackTrace
+	// received from remote services, see FromRemote
+	remote bool
 }
 
 func (ze *Error) Unwrap() error {
 	return ze.cause
 }
+
+// so errors.Is(err, def) reports whether err or any error in its chain is generated by def or its descendants,
+// an *Error target matches errors generated by the same def or its descendants
+func (ze *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Def:
+		return t.covers(ze.Def)
+	case *Error:
+		return t.Def.covers(ze.Def)
+	default:
+		return false
+	}
+}
+
+// sensitive messages are redacted according to the redact policy
 func (ze *Error) Error() string {
+	return ze.errorString(false)
+}
+
+func (ze *Error) errorString(render bool) string {
 	b := strings.Builder{}
 	b.WriteString(ze.Def.Code)
 	if ze.msg != `` {
 		b.WriteString(`(`)
-		b.WriteString(ze.msg)
+		policy := ze.Def.redactPolicy()
+		if policy.redacted(policy.Msg, render) {
+			b.WriteString(policy.mask())
+		} else {
+			b.WriteString(ze.msg)
+		}
+		b.WriteString(`)`)
+	} else if hasPlaceholder(ze.Def.Msg) {
+		b.WriteString(`(`)
+		b.WriteString(fillTemplate(ze.Def.Msg, ze.redactedData(render), Manager.missingKeyPolicy))
 		b.WriteString(`)`)
 	}
 	if ze.cause != nil {
 		b.WriteString(` | `)
-		b.WriteString(ze.cause.Error())
+		if zCause, ok := ze.cause.(*Error); ok {
+			b.WriteString(zCause.errorString(render))
+		} else {
+			b.WriteString(ze.cause.Error())
+		}
 	}
 	return b.String()
 }
 
+// errors are immutable, WithData returns a copy of the error with data added,
+// the data map of the original error is not modified
 func (ze *Error) WithData(data Data) *Error {
-	if ze.Data == nil {
-		ze.Data = make(Data, len(data)+2)
-	}
+	cp := ze.cloneWithData(len(data))
 	for k, v := range data {
-		ze.Data[k] = v
+		cp.Data[k] = v
 	}
-	return ze
+	return cp
 }
 
+// same as WithData, keys that are not string are formatted with %v
 func (ze *Error) WithKVs(kvs ...interface{}) *Error {
-	if ze.Data == nil {
-		ze.Data = make(Data, len(kvs))
-	}
+	cp := ze.cloneWithData((len(kvs) + 1) / 2)
 	for i := 0; i < len(kvs); i += 2 {
 		k, ok := kvs[i].(string)
 		if !ok {
@@ -87,36 +162,158 @@ func (ze *Error) WithKVs(kvs ...interface{}) *Error {
 		if i+1 < len(kvs) {
 			v = kvs[i+1]
 		}
-		ze.Data[k] = v
+		cp.Data[k] = v
+	}
+	return cp
+}
+
+func (ze *Error) cloneWithData(extra int) *Error {
+	cp := *ze
+	cp.Data = make(Data, len(ze.Data)+extra)
+	for k, v := range ze.Data {
+		cp.Data[k] = v
 	}
-	return ze
+	return &cp
 }
 
+// returns a copy of the error with ctx
 func (ze *Error) WithCtx(ctx context.Context) *Error {
-	ze.Ctx = ctx
-	return ze
+	cp := *ze
+	cp.Ctx = ctx
+	return &cp
+}
+
+// data of all layers of the chain merged in a new map,
+// data of outer layers overrides inner ones with the same keys
+func (ze *Error) AllData() Data {
+	layers := ze.layers()
+	out := make(Data)
+	for i := len(layers) - 1; i >= 0; i-- {
+		for k, v := range layers[i].Data {
+			out[k] = v
+		}
+	}
+	return out
 }
 
+// callers are resolved when it's called, not when errors are generated,
+// the location is the innermost one recorded in the chain,
+// the names of all layers are joined by '/' from inner to outer
 func (ze *Error) GetCaller() (string, string) {
-	return ze.callerLoc, ze.callerName
+	layers := ze.layers()
+	loc := ``
+	names := make([]string, 0, len(layers))
+	for i := len(layers) - 1; i >= 0; i-- {
+		l, n := layers[i].layerCaller()
+		if loc == `` {
+			loc = l
+		}
+		names = append(names, n)
+	}
+	return loc, strings.Join(names, `/`)
 }
 
-func (ze *Error) Render() Render {
+// layers of the chain generated by zerror, from outer to inner
+func (ze *Error) layers() []*Error {
+	out := []*Error{ze}
+	for e := ze; ; {
+		next := &Error{}
+		if ok := errors.As(e.cause, &next); !ok {
+			return out
+		}
+		out = append(out, next)
+		e = next
+	}
+}
+
+// return the stack trace captured by this error, or by the innermost wrapped error
+// that captured one, nil if stack trace is not captured
+func (ze *Error) StackTrace() StackTrace {
+	for _, layer := range ze.layers() {
+		if layer.stack != nil {
+			return layer.stack
+		}
+	}
+	return nil
+}
+
+// the def responded to clients, which is Internal if the error is caused by Internal,
+// so internal errors are not disguised by outer defs.
+// multiple errors in the chain are followed by their primary errors selected by the manager's multi policy
+func (ze *Error) ResponseDef() *Def {
+	if ze.Def != Internal && causedByInternal(ze.cause) {
+		return Internal
+	}
+	return ze.Def
+}
+
+func causedByInternal(err error) bool {
+	for err != nil {
+		switch e := err.(type) {
+		case *Error:
+			if Internal.covers(e.Def) {
+				return true
+			}
+			err = e.cause
+		case *Multi:
+			primary := e.Primary()
+			if primary == nil {
+				return false
+			}
+			err = primary
+		case interface{ Unwrap() []error }:
+			for _, err := range e.Unwrap() {
+				if causedByInternal(err) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() error }:
+			err = e.Unwrap()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// the returned handle should be released after the response is written, so the render can be reused
+func (ze *Error) Render() *RenderHandle {
 	def := ze.Def
-	s := renderPool.Get().(Render)
-	code := def.Code
-	if code != Internal.Code && Internal.Cause(ze.cause) {
-		code = Internal.Code
+	atomic.AddUint64(&counterOf(def).rendered, 1)
+	m := Manager
+	h := m.getRender()
+	s := h.Render
+	def = ze.ResponseDef()
+	s.SetCode(def.Code)
+	if m.respondMsgSet && m.respondMessage ||
+		!m.respondMsgSet && m.debugMode {
+		s.SetMessage(ze.errorString(true))
+	} else if m.catalog != nil || hasPlaceholder(def.Msg) {
+		s.SetMessage(ze.messageOf(def, true))
 	}
-	s.SetCode(code)
-	if Manager.respondMsgSet && Manager.respondMessage ||
-		!Manager.respondMsgSet && Manager.debugMode {
-		s.SetMessage(ze.Error())
+	if ds, ok := s.(DetailsRender); ok {
+		if details := ze.publicData(); details != nil {
+			ds.SetDetails(details)
+		}
 	}
-	return s
+	if es, ok := s.(ErrorRender); ok {
+		es.RenderError(ze, def)
+	}
+	m.runRenderHooks(ze, s)
+	return h
+}
+
+// def is error only to be used as target of errors.Is, like errors.Is(err, NotFound),
+// it's not an error generated by def, use New or Wrap to generate errors,
+// a def returned as error is converted by From to an error generated by it
+func (def *Def) Error() string {
+	return def.Code
 }
 
+// panics if k is registered by ExtKey with another type, use ExtKey.Set for type safety
 func (def *Def) Extend(k string, v interface{}) *Def {
+	checkExtValue(k, v)
 	if def.extensions == nil {
 		def.extensions = make(map[string]interface{})
 	}
@@ -124,31 +321,31 @@ func (def *Def) Extend(k string, v interface{}) *Def {
 	return def
 }
 
-func (def *Def) GetExtension(key string) (interface{}, bool) {
-	if def.extensions == nil {
-		return nil, false
-	}
-	value, ok := def.extensions[key]
-	return value, ok
+// extensions of ancestors are looked up if def doesn't have the key
+func (def *Def) GetExtension(key string) (value interface{}, ok bool) {
+	def.lineage(func(d *Def) bool {
+		value, ok = d.extensions[key]
+		return !ok
+	})
+	return
 }
 
 func (def *Def) wrapf(err error, skip int, format string, args ...interface{}) *Error {
 
-	l, n := GetCaller(def, skip)
+	atomic.AddUint64(&counterOf(def).created, 1)
 	zCause := &Error{}
 	zErr := &Error{
 		cause: err,
 		Def:   def,
 	}
+	zErr.callerPC = callerPC(skip)
 	if ok := errors.As(err, &zCause); ok {
-		zErr.ZContext = zCause.ZContext
-		zErr.callerName += `/` + n
+		zErr.Ctx = zCause.Ctx
 	} else {
-		zErr.ZContext = ZContext{
-			callerLoc:  l,
-			callerName: n,
-			Data:       make(Data),
-		}
+		zCause = nil
+	}
+	if Manager.captureStack(def) && (zCause == nil || zCause.StackTrace() == nil) {
+		zErr.stack = callers(skip, Manager.stackDepth)
 	}
 	if format != `` {
 		zErr.msg = fmt.Sprintf(format, args...)
@@ -157,54 +354,75 @@ func (def *Def) wrapf(err error, skip int, format string, args ...interface{}) *
 }
 
 func (def *Def) Wrap(err error) *Error {
-	return def.wrapf(err, 3, ``)
+	return Manager.runWrapHooks(def.wrapf(err, 3, ``))
 }
 
 func (def *Def) Wrapf(err error, format string, args ...interface{}) *Error {
-	return def.wrapf(err, 3, format, args...)
+	return Manager.runWrapHooks(def.wrapf(err, 3, format, args...))
 }
 
 func (def *Def) WithMsg(msg string) *Error {
-	return def.wrapf(nil, 3, msg)
+	return Manager.runCreateHooks(def.wrapf(nil, 3, msg))
 }
 
 func (def *Def) New() *Error {
-	return def.wrapf(nil, 3, ``)
+	return Manager.runCreateHooks(def.wrapf(nil, 3, ``))
 }
 
-func (def *Def) Errorf(format string, args ...interface{}) *Error {
-	err := errors.New(fmt.Sprintf(format, args...))
-	return def.wrapf(err, 3, ``)
+// same as New, but the error has ctx, which create hooks receive,
+// use it instead of New(...).WithCtx(ctx) if hooks read the context, like hooks adding trace ids
+func (def *Def) NewCtx(ctx context.Context) *Error {
+	ze := def.wrapf(nil, 3, ``)
+	ze.Ctx = ctx
+	return Manager.runCreateHooks(ze)
 }
 
-var renderPool = sync.Pool{
-	New: func() interface{} {
-		render := Manager.render()
-		reset, ok := render.(Resetter)
-		if ok {
-			reset.Reset()
-		}
-		return render
-	},
+// same as WithMsg, but the error has ctx, which create hooks receive
+func (def *Def) WithMsgCtx(ctx context.Context, msg string) *Error {
+	ze := def.wrapf(nil, 3, msg)
+	ze.Ctx = ctx
+	return Manager.runCreateHooks(ze)
 }
 
+// same as Wrap, but the error has ctx instead of the context of err, which wrap hooks receive
+func (def *Def) WrapCtx(ctx context.Context, err error) *Error {
+	ze := def.wrapf(err, 3, ``)
+	ze.Ctx = ctx
+	return Manager.runWrapHooks(ze)
+}
+
+func (def *Def) Errorf(format string, args ...interface{}) *Error {
+	err := errors.New(fmt.Sprintf(format, args...))
+	return Manager.runCreateHooks(def.wrapf(err, 3, ``))
+}
+
+// report whether err or any error in its chain is generated by def or its descendants,
+// all branches of multiple errors are searched
 func (def *Def) Cause(err error) bool {
-	zerr := &Error{}
-	for {
-		if ok := errors.As(err, &zerr); ok {
-			if zerr.Def == def {
+	switch e := err.(type) {
+	case nil:
+		return false
+	case *Error:
+		if def.covers(e.Def) {
+			return true
+		}
+		return def.Cause(e.cause)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			if def.Cause(err) {
 				return true
-			} else {
-				err = zerr.cause
 			}
-		} else {
-			return false
 		}
+		return false
+	case interface{ Unwrap() error }:
+		return def.Cause(e.Unwrap())
+	default:
+		return false
 	}
 }
 
 func FromCode(code string) (*Error, bool) {
-	def, ok := defMap[code]
+	def, ok := lookupDef(code)
 	if !ok {
 		return nil, ok
 	}
@@ -1,11 +1,21 @@
 package zerror
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
+	"expvar"
 	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -64,7 +74,7 @@ func ExampleGetCaller() {
 	// ExampleGetCaller
 }
 
-func ExampleNested() {
+func Example_nested() {
 	unregister()
 	data := &TestErr{
 		TestErr1:            &Def{Msg: `msg1`},
@@ -74,16 +84,18 @@ func ExampleNested() {
 	initErrGroup(data)
 	ze := data.TestErr1.Wrap(data.Err.Wrap(errors.New(`original-error`)))
 	fmt.Println(ze.Error())
-	fmt.Println(ze.callerName, ze.Def.Code)
+	_, name := ze.GetCaller()
+	fmt.Println(name, ze.Def.Code)
 
 	ze = data.TestErr1.Wrapf(data.Err.Wrap(errors.New(`original-error`)), `wrap message: %s`, `ad`)
 	fmt.Println(ze.Error())
-	fmt.Println(ze.callerName, ze.Def.Code)
+	_, name = ze.GetCaller()
+	fmt.Println(name, ze.Def.Code)
 	// Output:
 	// test-err:test-err1 | custom-code | original-error
-	//ExampleNested/ExampleNested test-err:test-err1
+	//Example_nested/Example_nested test-err:test-err1
 	//test-err:test-err1(wrap message: ad) | custom-code | original-error
-	//ExampleNested/ExampleNested test-err:test-err1
+	//Example_nested/Example_nested test-err:test-err1
 }
 
 type customeRsp struct {
@@ -102,7 +114,7 @@ func (c *customeRsp) Error() string {
 	return c.Msg
 }
 
-func ExampleCustomResponser() {
+func Example_customResponser() {
 	unregister()
 	m := Init(
 		WithRender(func() Render {
@@ -122,19 +134,19 @@ func ExampleCustomResponser() {
 	// {"A":"zerror:internal","Msg":"zerror:internal(original msg)"}
 }
 
-func ExampleDefaultDef() {
+func Example_defaultDef() {
 
 	unregister()
 	m := Init(DefaultStatus(500))
 	data := &TestErr{}
 	m.RegisterGroups(data)
 	defer unregister()
-	fmt.Printf("%+v\n", data.Err)
+	fmt.Printf("%+v\n", *data.Err)
 	// Output:
-	// &{Code:test-err:err Msg: Description: Status:500 extensions:map[]}
+	// {Code:test-err:err Msg: Description: Status:500 Severity:error Retry:unspecified RetryAfter:0s Parent:<nil> extensions:map[] remote:false}
 }
 
-func ExampleDef_Is() {
+func ExampleDef_Cause() {
 
 	originalError := errors.New(`original error`)
 	def := &Def{Code: `def`}
@@ -159,7 +171,7 @@ func ExampleDef_Is() {
 }
 
 func BenchmarkDef_Wrap(b *testing.B) {
-
+	b.ReportAllocs()
 	originalError := errors.New(`original error`)
 	def := &Def{Msg: `default`}
 	for i := 0; i < b.N; i++ {
@@ -167,6 +179,40 @@ func BenchmarkDef_Wrap(b *testing.B) {
 	}
 }
 
+// the cost of resolving caller when errors are generated, which is paid by every error before callers are lazy
+func BenchmarkGetCaller(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		GetCaller(Internal, 1)
+	}
+}
+
+// the cost of errors whose callers are read
+func BenchmarkDef_WrapGetCaller(b *testing.B) {
+	b.ReportAllocs()
+	originalError := errors.New(`original error`)
+	def := &Def{Msg: `default`}
+	for i := 0; i < b.N; i++ {
+		def.wrapf(originalError, 1, ``).GetCaller()
+	}
+}
+
+func BenchmarkError_Render(b *testing.B) {
+	b.ReportAllocs()
+	ze := NotFound.New()
+	for i := 0; i < b.N; i++ {
+		ze.Render()
+	}
+}
+
+func BenchmarkError_RenderRelease(b *testing.B) {
+	b.ReportAllocs()
+	ze := NotFound.New()
+	for i := 0; i < b.N; i++ {
+		ze.Render().Release()
+	}
+}
+
 func TestData(t *testing.T) {
 	zerr := Internal.New().
 		WithData(map[string]interface{}{
@@ -192,3 +238,801 @@ func TestFromCode(t *testing.T) {
 	require.Equal(t, true, ok)
 	require.Equal(t, zerr.Code, BadRequest.Code)
 }
+
+func TestStackTrace(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithStackTrace(8, func(def *Def) bool {
+		return def == Internal
+	}))
+
+	require.Nil(t, BadRequest.New().StackTrace())
+
+	inner := Internal.New()
+	st := inner.StackTrace()
+	require.NotEmpty(t, st)
+	frames := st.Frames()
+	require.True(t, strings.HasSuffix(frames[0].Function, `.TestStackTrace`), frames[0].Function)
+	require.LessOrEqual(t, len(frames), 8)
+
+	outer := Internal.Wrap(inner)
+	require.Nil(t, outer.stack)
+	require.Equal(t, st, outer.StackTrace())
+	require.Contains(t, st.String(), `error_test.go`)
+}
+
+func TestFormat(t *testing.T) {
+	def := &Def{Code: `def`}
+	def1 := &Def{Code: `def1`}
+	wrapped := def1.Wrapf(def.Wrap(errors.New(`original error`)).WithKVs(`k`, `v`), `wrap %d`, 1)
+
+	require.Equal(t, wrapped.Error(), fmt.Sprintf(`%s`, wrapped))
+	require.Equal(t, wrapped.Error(), fmt.Sprintf(`%v`, wrapped))
+	require.Equal(t, `"def1(wrap 1) | def | original error"`, fmt.Sprintf(`%q`, wrapped))
+	expected := "def1: wrap 1\n" +
+		"\tcaller: TestFormat\n" +
+		"def\n" +
+		"\tcaller: TestFormat\n" +
+		"\tdata: k=v\n" +
+		"original error"
+	require.Equal(t, expected, fmt.Sprintf(`%+v`, wrapped))
+
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithStackTrace(8, nil))
+	wrapped = BadRequest.Wrap(fmt.Errorf(`query: %w`, NotFound.Wrap(errors.New(`original error`)).WithKVs(`id`, 7)))
+	verbose := fmt.Sprintf(`%+v`, wrapped)
+	require.Contains(t, verbose, "zerror:bad_request\n\tcaller: TestFormat\n"+
+		"query:\n"+
+		"zerror:not_found\n\tcaller: TestFormat\n\tdata: id=7\n\tstack:\n")
+	require.True(t, strings.HasSuffix(verbose, "\noriginal error"))
+}
+
+func TestJSON(t *testing.T) {
+	unknown := &Def{Code: `unknown`, Status: StatusUnavailable}
+	ze := NotFound.Wrapf(
+		unknown.Wrap(errors.New(`original error`)).WithKVs(`id`, `1`),
+		`user %s`, `1`,
+	)
+	b, err := json.Marshal(ze)
+	require.Nil(t, err)
+
+	decoded := &Error{}
+	require.Nil(t, json.Unmarshal(b, decoded))
+	require.Equal(t, ze.Error(), decoded.Error())
+	require.True(t, decoded.Def == NotFound)
+	require.True(t, decoded.Remote())
+	require.False(t, ze.Remote())
+	require.Nil(t, decoded.Data)
+	require.Equal(t, Data{`id`: `1`}, decoded.AllData())
+	loc, name := ze.GetCaller()
+	decodedLoc, decodedName := decoded.GetCaller()
+	require.Equal(t, loc, decodedLoc)
+	require.Equal(t, name, decodedName)
+
+	inner := decoded.Unwrap().(*Error)
+	require.Equal(t, unknown.Code, inner.Code)
+	require.Equal(t, unknown.Status, inner.Status)
+	require.Equal(t, `original error`, inner.Unwrap().Error())
+
+	require.NotNil(t, json.Unmarshal([]byte(`{"msg":"no code"}`), &Error{}))
+
+	for _, ze := range []*Error{
+		BadRequest.Wrap(fmt.Errorf(`query: %w`, NotFound.Wrap(errors.New(`original error`)).WithKVs(`id`, 7))),
+		Internal.Wrap(Join(errors.New(`first`), NotFound.New().WithKVs(`id`, 7))),
+		Internal.Wrap(errors.Join(errors.New(`first`), fmt.Errorf(`second: %w`, NotFound.New().WithKVs(`id`, 7)))),
+	} {
+		b, err := json.Marshal(ze)
+		require.Nil(t, err)
+		decoded := &Error{}
+		require.Nil(t, json.Unmarshal(b, decoded))
+		require.Equal(t, ze.Error(), decoded.Error())
+		require.True(t, NotFound.Cause(decoded), string(b))
+		require.Equal(t, Data{`id`: float64(7)}, decoded.AllData())
+	}
+	b, err = json.Marshal(Internal.Wrap(Join(BadRequest.New(), NotFound.New())))
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(b, decoded))
+	zerr := &Error{}
+	require.True(t, errors.As(decoded.Unwrap(), &zerr))
+	require.True(t, zerr.Def == BadRequest)
+
+	secret := BadRequest.New().WithKVs(`password`, `p`, `id`, `1`)
+	b, err = json.Marshal(secret)
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(b, decoded))
+	require.Equal(t, Data{`password`: defaultMask, `id`: `1`}, decoded.Data)
+	b, err = json.Marshal(secret.Unredacted())
+	require.Nil(t, err)
+	require.Nil(t, json.Unmarshal(b, decoded))
+	require.Equal(t, Data{`password`: `p`, `id`: `1`}, decoded.Data)
+}
+
+func TestMulti(t *testing.T) {
+	require.Nil(t, Join(nil, nil))
+
+	original := errors.New(`original error`)
+	badRequest := BadRequest.New()
+	notFound := NotFound.Wrap(original)
+	internal := Internal.New()
+	multi := Join(badRequest, fmt.Errorf(`wrapped: %w`, internal), notFound)
+	require.Equal(t, `zerror:bad_request; wrapped: zerror:internal; zerror:not_found | original error`, multi.Error())
+
+	require.True(t, NotFound.Cause(multi))
+	require.True(t, Internal.Cause(BadRequest.Wrap(multi)))
+	require.False(t, Forbidden.Cause(multi))
+	require.True(t, errors.Is(multi, original))
+
+	zerr := &Error{}
+	require.True(t, errors.As(multi, &zerr))
+	require.True(t, zerr == internal)
+	require.Equal(t, StatusInternal, multi.(*Multi).Status())
+	require.Equal(t, Internal.Code, multi.(*Multi).Render().Render.(*StdResponse).Code)
+
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithMultiPolicy(FirstError))
+	require.True(t, errors.As(multi, &zerr))
+	require.True(t, zerr == badRequest)
+	nested := NotFound.Wrap(Join(BadRequest.New(), Internal.New()))
+	require.True(t, nested.ResponseDef() == NotFound)
+	require.Equal(t, NotFound.Code, nested.Render().Render.(*StdResponse).Code)
+	Init(WithMultiPolicy(MostSevere))
+	require.True(t, nested.ResponseDef() == Internal)
+
+	require.False(t, errors.As(Join(original), &zerr))
+	require.Equal(t, StatusInternal, Join(original).(*Multi).Status())
+}
+
+func TestErrorsIs(t *testing.T) {
+	original := errors.New(`original error`)
+	ze := BadRequest.Wrap(fmt.Errorf(`wrapped: %w`, NotFound.Wrap(original)))
+	require.True(t, errors.Is(ze, NotFound))
+	require.True(t, errors.Is(ze, BadRequest))
+	require.True(t, errors.Is(ze, original))
+	require.True(t, errors.Is(ze, NotFound.New()))
+	require.False(t, errors.Is(ze, Internal))
+	require.False(t, errors.Is(original, NotFound))
+	require.True(t, errors.Is(Join(original, ze), NotFound))
+}
+
+func TestCopyOnWriteData(t *testing.T) {
+	inner := BadRequest.New().WithKVs(`k`, `inner`, `i`, 1)
+	outer1 := Internal.Wrap(inner).WithKVs(`k`, `outer1`)
+	outer2 := Internal.Wrap(inner)
+	added := outer2.WithKVs(`o`, 2)
+
+	require.Equal(t, Data{`k`: `inner`, `i`: 1}, inner.Data)
+	require.Equal(t, Data{`k`: `outer1`}, outer1.Data)
+	require.Nil(t, outer2.Data)
+	require.Equal(t, Data{`k`: `outer1`, `i`: 1}, outer1.AllData())
+	require.Equal(t, Data{`k`: `inner`, `i`: 1, `o`: 2}, added.AllData())
+
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			Internal.Wrap(inner).WithKVs(`goroutine`, i).AllData()
+			done <- struct{}{}
+		}(i)
+	}
+	<-done
+	<-done
+	require.Equal(t, Data{`k`: `inner`, `i`: 1}, inner.Data)
+
+	_, name := outer1.GetCaller()
+	require.Equal(t, `TestCopyOnWriteData/TestCopyOnWriteData`, name)
+
+	merged := Internal.New()
+	merged.Merge(&inner.ZContext)
+	require.Equal(t, Data{`k`: `inner`, `i`: 1}, merged.Data)
+}
+
+func TestValue(t *testing.T) {
+	userID := NewKey[int](`user_id`)
+	name := NewKey[string](`name`)
+
+	inner := WithValue(NotFound.New(), userID, 1)
+	inner = WithValue(inner, name, `inner`)
+	outer := WithValue(Internal.Wrap(inner), name, `outer`).WithKVs(`user_id`, `not int`)
+	err := fmt.Errorf(`wrapped: %w`, outer)
+
+	id, ok := Value(err, userID)
+	require.True(t, ok)
+	require.Equal(t, 1, id)
+
+	n, ok := Value(err, name)
+	require.True(t, ok)
+	require.Equal(t, `outer`, n)
+
+	_, ok = Value(err, NewKey[bool](`missing`))
+	require.False(t, ok)
+	_, ok = Value(errors.New(`original error`), userID)
+	require.False(t, ok)
+}
+
+func TestRedact(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithRedactPolicy(RedactPolicy{
+		Keys: map[string]Sensitivity{
+			`Password`: SensitiveSecret,
+			`email`:    SensitivePII,
+			`sql`:      SensitiveInternal,
+		},
+		Redact:       SensitiveSecret,
+		RenderRedact: SensitivePII | SensitiveInternal,
+	}), SetDebugMode(true))
+
+	secretDef := ExtRedactPolicy.Set(&Def{Code: `secret`}, RedactPolicy{
+		Msg:  SensitiveSecret,
+		Mask: `***`,
+	})
+	ze := Internal.Wrapf(
+		secretDef.WithMsg(`token abc`).WithKVs(`password`, `p`, `email`, `e`),
+		`query failed`,
+	).WithKVs(`sql`, `select`)
+
+	require.Equal(t, `zerror:internal(query failed) | secret(***)`, ze.Error())
+	require.Equal(t, Data{`password`: `***`, `email`: `e`, `sql`: `select`}, ze.RedactedData())
+	require.Equal(t, Data{`password`: `***`, `email`: `***`, `sql`: `[REDACTED]`}, ze.redactedData(true))
+	require.Equal(t, ze.Error(), ze.Render().Render.(*StdResponse).Msg)
+	require.NotContains(t, fmt.Sprintf(`%+v`, ze), `token abc`)
+	require.NotContains(t, fmt.Sprintf(`%+v`, ze), `password=p`)
+	require.Equal(t, `p`, ze.AllData()[`password`])
+}
+
+func TestRetry(t *testing.T) {
+	unavailable := &Def{Code: `unavailable`, Retry: Retryable}
+	limited := &Def{Code: `limited`, RetryAfter: 20 * time.Millisecond}
+	invalid := &Def{Code: `invalid`, Retry: NonRetryable}
+
+	require.True(t, IsRetryable(BadRequest.Wrap(unavailable.New())))
+	require.True(t, IsRetryable(limited.New()))
+	require.False(t, IsRetryable(invalid.Wrap(unavailable.New())))
+	require.False(t, IsRetryable(BadRequest.New()))
+	require.False(t, IsRetryable(errors.New(`original error`)))
+	after, ok := RetryAfter(Internal.Wrap(limited.New()))
+	require.True(t, ok)
+	require.Equal(t, 20*time.Millisecond, after)
+
+	policy := RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2, Jitter: 0.5}
+	attempts := 0
+	err := Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return unavailable.New()
+	})
+	require.True(t, unavailable.Cause(err))
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	start := time.Now()
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return limited.New()
+		}
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, 2, attempts)
+	require.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	attempts = 0
+	err = Retry(context.Background(), policy, func(ctx context.Context) error {
+		attempts++
+		return invalid.New()
+	})
+	require.True(t, invalid.Cause(err))
+	require.Equal(t, 1, attempts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	attempts = 0
+	err = Retry(ctx, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Hour}, func(ctx context.Context) error {
+		attempts++
+		return unavailable.New()
+	})
+	require.True(t, unavailable.Cause(err))
+	require.Equal(t, 1, attempts)
+}
+
+type timeoutErr struct{}
+
+func (timeoutErr) Error() string   { return `i/o timeout` }
+func (timeoutErr) Timeout() bool   { return true }
+func (timeoutErr) Temporary() bool { return true }
+
+func TestFrom(t *testing.T) {
+	require.Nil(t, From(nil))
+
+	ze := BadRequest.New()
+	require.True(t, From(fmt.Errorf(`wrapped: %w`, ze)) == ze)
+	var returned error = NotFound
+	fromDef := From(returned)
+	require.True(t, fromDef.Def == NotFound)
+	require.Nil(t, fromDef.Unwrap())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-ctx.Done()
+	require.True(t, DeadlineExceeded.Cause(From(ctx.Err())))
+	require.Equal(t, StatusDeadlineExceeded, From(ctx.Err()).Status)
+	require.True(t, Canceled.Cause(From(context.Canceled)))
+	require.True(t, NotFound.Cause(From(fmt.Errorf(`query: %w`, sql.ErrNoRows))))
+	_, err := os.Open(`/not/exist`)
+	require.True(t, NotFound.Cause(From(err)))
+	require.True(t, DeadlineExceeded.Cause(From(&net.OpError{Op: `read`, Err: timeoutErr{}})))
+
+	original := errors.New(`original error`)
+	zerr := From(original)
+	require.True(t, Internal.Cause(zerr))
+	require.True(t, errors.Is(zerr, original))
+	_, name := zerr.GetCaller()
+	require.Equal(t, `TestFrom`, name)
+
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithClassifiers(
+		ClassifyIs(original, BadRequest),
+		ClassifyIs(sql.ErrNoRows, AlreadyExists),
+	))
+	require.True(t, BadRequest.Cause(From(original)))
+	require.True(t, AlreadyExists.Cause(From(sql.ErrNoRows)))
+}
+
+func panicAt() {
+	var m map[string]int
+	m[`key`] = 1
+}
+
+func TestRecover(t *testing.T) {
+	err := func() (err error) {
+		defer Recover(&err)
+		panicAt()
+		return nil
+	}()
+	zerr := &Error{}
+	require.True(t, errors.As(err, &zerr))
+	require.True(t, Internal.Cause(err))
+	var runtimeErr runtime.Error
+	require.True(t, errors.As(err, &runtimeErr))
+	v, ok := Value(err, PanicValue)
+	require.True(t, ok)
+	require.Equal(t, runtimeErr, v)
+	loc, name := zerr.GetCaller()
+	require.Equal(t, `panicAt`, name)
+	require.Contains(t, loc, `error_test.go`)
+	require.True(t, strings.HasSuffix(zerr.StackTrace().Frames()[0].Function, `.panicAt`))
+
+	err = <-SafeGo(func() error {
+		panic(`boom`)
+	})
+	require.True(t, Internal.Cause(err))
+	require.Equal(t, `zerror:internal(panic) | boom`, err.Error())
+
+	original := errors.New(`original error`)
+	require.Equal(t, original, <-SafeGo(func() error { return original }))
+	require.Nil(t, <-SafeGo(func() error { return nil }))
+}
+
+func newAt(def *Def, msg string) *Error {
+	return def.WithMsg(msg)
+}
+
+func TestFingerprint(t *testing.T) {
+	newErr := func(msg string) *Error {
+		return Internal.Wrap(newAt(NotFound, msg).WithKVs(`id`, msg))
+	}
+	e1, e2 := newErr(`a`), newErr(`b`)
+	require.Equal(t, e1.Fingerprint(), e2.Fingerprint())
+	require.NotEqual(t, e1.Fingerprint(), Internal.Wrap(newAt(BadRequest, `a`)).Fingerprint())
+	require.NotEqual(t, e1.Fingerprint(), Internal.Wrap(NotFound.WithMsg(`a`)).Fingerprint())
+	require.Equal(t, NotFound.New().Fingerprint(),
+		NotFound.New().Fingerprint())
+
+	d := NewDeduper(30 * time.Millisecond)
+	ok, suppressed := d.Allow(e1)
+	require.True(t, ok)
+	require.Equal(t, 0, suppressed)
+	ok, _ = d.Allow(e2)
+	require.False(t, ok)
+	ok, _ = d.Allow(fmt.Errorf(`wrapped: %w`, e2))
+	require.False(t, ok)
+	ok, _ = d.Allow(BadRequest.New())
+	require.True(t, ok)
+	ok, _ = d.Allow(errors.New(`original error`))
+	require.True(t, ok)
+	ok, _ = d.Allow(errors.New(`original error`))
+	require.False(t, ok)
+
+	time.Sleep(30 * time.Millisecond)
+	ok, suppressed = d.Allow(e1)
+	require.True(t, ok)
+	require.Equal(t, 2, suppressed)
+
+	time.Sleep(60 * time.Millisecond)
+	d.Allow(e1)
+	d.mu.Lock()
+	require.Len(t, d.seen, 1)
+	d.mu.Unlock()
+}
+
+func TestMetrics(t *testing.T) {
+	def := &Def{Code: `metrics:def`, Status: StatusUnavailable}
+	// counters are kept by code, reset it so the test can run more than once
+	counters.Delete(def.Code)
+	def.New()
+	def.Wrap(errors.New(`original error`)).Render()
+	(&Def{Code: def.Code, Status: StatusUnavailable}).New()
+
+	var found DefMetric
+	for _, m := range metricsSnapshot() {
+		if m.Code == def.Code {
+			found = m
+		}
+	}
+	require.Equal(t, DefMetric{Code: def.Code, Status: StatusUnavailable, Created: 3, Rendered: 1}, found)
+	require.Contains(t, expvar.Get(`zerror`).String(), `"code":"metrics:def"`)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, `/metrics`, nil))
+	body := rec.Body.String()
+	require.Contains(t, body, "# TYPE zerror_errors_created_total counter\n")
+	require.Contains(t, body, `zerror_errors_created_total{code="metrics:def",status="503",group=""} 3`)
+	require.Equal(t, 1, strings.Count(body, `zerror_errors_created_total{code="metrics:def"`))
+	require.Contains(t, body, `zerror_errors_rendered_total{code="metrics:def",status="503",group=""} 1`)
+	require.Contains(t, body, `zerror_errors_created_total{code="zerror:not_found",status="404",group="zerror"}`)
+}
+
+type traceKey struct{}
+
+func TestHooks(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	manager := Init()
+	var events []string
+	manager.OnCreate(func(ctx context.Context, ze *Error) *Error {
+		events = append(events, `create:`+ze.Code)
+		traceID, ok := ctx.Value(traceKey{}).(string)
+		if !ok {
+			traceID = `t1`
+		}
+		return ze.WithKVs(`trace_id`, traceID)
+	})
+	manager.OnWrap(func(ctx context.Context, ze *Error) *Error {
+		events = append(events, fmt.Sprintf(`wrap:%s:%v`, ze.Code, ctx.Value(traceKey{})))
+		return nil
+	})
+	manager.OnRender(func(ctx context.Context, ze *Error, r Render) {
+		events = append(events, fmt.Sprintf(`render:%s:%v`, ze.Code, ctx.Value(traceKey{})))
+	})
+
+	ze := NotFound.New()
+	require.Equal(t, `t1`, ze.Data[`trace_id`])
+	BadRequest.Errorf(`bad %s`, `request`)
+	wrapped := Internal.Wrap(ze)
+	require.Equal(t, Data{`trace_id`: `t1`}, wrapped.AllData())
+	From(errors.New(`original error`))
+	ctx := context.WithValue(context.Background(), traceKey{}, `t2`)
+	wrapped.WithCtx(ctx).Render()
+	created := NotFound.NewCtx(ctx)
+	require.Equal(t, Data{`trace_id`: `t2`}, created.Data)
+	_, name := created.GetCaller()
+	require.Equal(t, `TestHooks`, name)
+	require.Equal(t, `t2`, BadRequest.WithMsgCtx(ctx, `bad request`).Data[`trace_id`])
+	Internal.Wrap(Internal.WrapCtx(ctx, errors.New(`original error`)))
+	require.Equal(t, []string{
+		`create:zerror:not_found`,
+		`create:zerror:bad_request`,
+		`wrap:zerror:internal:<nil>`,
+		`wrap:zerror:internal:<nil>`,
+		`render:zerror:internal:t2`,
+		`create:zerror:not_found`,
+		`create:zerror:bad_request`,
+		`wrap:zerror:internal:t2`,
+		`wrap:zerror:internal:t2`,
+	}, events)
+}
+
+func TestLocale(t *testing.T) {
+	require.Equal(t, []string{`zh-CN`, `en`, `zh`}, ParseAcceptLanguage(`zh;q=0.8, en;q=0.9, zh-CN, fr;q=0, *;q=0.1`))
+
+	dir := t.TempDir()
+	jsonFile := dir + `/zh.json`
+	yamlFile := dir + `/en.yaml`
+	require.Nil(t, os.WriteFile(jsonFile, []byte(`{"zerror:not_found": "未找到", "zerror:bad_request": {"msg": "请求错误", "desc": "参数错误"}}`), 0644))
+	require.Nil(t, os.WriteFile(yamlFile, []byte("zerror:not_found: resource not found\n"), 0644))
+	catalog := NewCatalog()
+	require.Nil(t, catalog.LoadFile(`zh`, jsonFile))
+	require.Nil(t, catalog.LoadFile(`en`, yamlFile))
+	catalog.Register(`zh_TW`, NotFound.Code, CatalogEntry{Msg: `找不到`})
+
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithCatalog(catalog, `en`))
+
+	require.Equal(t, `找不到`, NotFound.LocalizedMsg(`zh-TW`))
+	require.Equal(t, `未找到`, NotFound.LocalizedMsg(`zh-CN`))
+	require.Equal(t, `resource not found`, NotFound.LocalizedMsg(`fr`))
+	require.Equal(t, `参数错误`, BadRequest.LocalizedDescription(`zh`))
+	require.Equal(t, `forbidden`, Forbidden.LocalizedMsg(`zh`))
+	child := NotFound.Derive(`user_not_found`)
+	require.Equal(t, `未找到`, child.LocalizedMsg(`zh-CN`))
+	child.Msg = `user not found`
+	require.Equal(t, `user not found`, child.LocalizedMsg(`zh-CN`))
+
+	ctx := WithAcceptLanguage(context.Background(), `zh-CN,zh;q=0.9`)
+	ze := NotFound.New().WithCtx(ctx)
+	require.Equal(t, `未找到`, ze.Message())
+	rsp := ze.Render().Render.(*StdResponse)
+	require.Equal(t, StdResponse{Code: NotFound.Code, Msg: `未找到`}, *rsp)
+	rsp = NotFound.New().Render().Render.(*StdResponse)
+	require.Equal(t, `resource not found`, rsp.Msg)
+}
+
+func TestTemplate(t *testing.T) {
+	require.Equal(t, `user 1 not found in {db}, {x}`, fillTemplate(`user {id} not found in {db}, {{x}}`, Data{`id`: 1}, MissingKeyKeep))
+	require.Equal(t, `user  not found: {`, fillTemplate(`user {id} not found: {`, nil, MissingKeyEmpty))
+	require.Equal(t, `user <missing>`, fillTemplate(`user {id}`, nil, MissingKeyMark))
+
+	userNotFound := &Def{Code: `user:not_found`, Msg: `user {user_id} not found, password: {password}`, Status: StatusNotFound}
+	ze := Internal.Wrap(userNotFound.New().WithKVs(`user_id`, 1, `password`, `p`)).WithKVs(`user_id`, 2)
+	require.Equal(t, `zerror:internal | user:not_found(user 1 not found, password: [REDACTED])`, ze.Error())
+	inner := ze.Unwrap().(*Error)
+	require.Equal(t, `user 1 not found, password: [REDACTED]`, inner.Message())
+	rsp := inner.Render().Render.(*StdResponse)
+	require.Equal(t, inner.Message(), rsp.Msg)
+	require.Equal(t, `user:not_found(wrapped)`, userNotFound.WithMsg(`wrapped`).Error())
+
+	require.True(t, hasPlaceholder(`a {{b}} {c}`))
+	for _, s := range []string{`expected {`, `expected }`, `{{b}}`, `{}`, `a {b`} {
+		require.False(t, hasPlaceholder(s), s)
+	}
+	brace := &Def{Code: `x`, Msg: `expected {`}
+	require.Equal(t, `x`, brace.New().Error())
+	require.Equal(t, `expected {`, brace.New().Message())
+	require.Equal(t, `escaped {b}`, (&Def{Code: `x`, Msg: `escaped {{b}}`}).New().Message())
+}
+
+type AuthErr struct {
+	TokenExpired *Def
+	TokenInvalid *Def
+}
+
+func TestInherit(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	unregister()
+	defer unregister()
+	logLevel := `log_level`
+	parent := (&Def{Code: `parent`, Status: StatusUnauthenticated, Msg: `unauthenticated`}).Extend(logLevel, `info`)
+	group := &AuthErr{
+		TokenExpired: &Def{Parent: parent},
+		TokenInvalid: (&Def{Parent: parent, Status: StatusBadRequest, Msg: `token invalid`}).Extend(logLevel, `warn`),
+	}
+	Init().RegisterGroups(group)
+
+	require.Equal(t, StatusUnauthenticated, group.TokenExpired.Status)
+	require.Equal(t, `unauthenticated`, group.TokenExpired.Msg)
+	require.Equal(t, StatusBadRequest, group.TokenInvalid.Status)
+	require.Equal(t, `token invalid`, group.TokenInvalid.Msg)
+	lvl, _ := group.TokenExpired.GetExtension(logLevel)
+	require.Equal(t, `info`, lvl)
+	lvl, _ = group.TokenInvalid.GetExtension(logLevel)
+	require.Equal(t, `warn`, lvl)
+
+	ze := Internal.Wrap(group.TokenExpired.New())
+	require.True(t, parent.Cause(ze))
+	require.True(t, errors.Is(ze, parent))
+	require.False(t, group.TokenInvalid.Cause(ze))
+	require.False(t, errors.Is(parent.New(), group.TokenExpired))
+
+	grandchild := group.TokenExpired.Derive(`grandchild`)
+	require.Equal(t, StatusUnauthenticated, grandchild.Status)
+	require.True(t, errors.Is(grandchild.New(), parent))
+
+	b, err := json.Marshal(group.TokenExpired)
+	require.Nil(t, err)
+	require.Equal(t, `{"code":"auth-err:token-expired","msg":"unauthenticated","desc":"","status":401,"severity":"info","parent":"parent"}`, string(b))
+
+	a, b1, c := &Def{Code: `a`}, &Def{Code: `b`}, &Def{Code: `c`}
+	a.Parent, b1.Parent, c.Parent = b1, c, b1
+	require.False(t, parent.covers(a))
+	require.True(t, c.covers(a))
+	_, ok := a.GetExtension(logLevel)
+	require.False(t, ok)
+	require.Panics(t, func() { a.inherit() })
+	require.Panics(t, func() { b1.inherit() })
+}
+
+func TestSeverity(t *testing.T) {
+	require.Equal(t, SeverityError, StatusSeverity(StatusUnavailable))
+	require.Equal(t, SeverityInfo, StatusSeverity(StatusNotFound))
+	require.Equal(t, SeverityDebug, StatusSeverity(StatusOk))
+
+	critical := &Def{Code: `critical`, Status: StatusBadRequest, Severity: SeverityCritical}
+	require.Equal(t, SeverityInfo, NotFound.New().Severity())
+	require.Equal(t, SeverityError, NotFound.Wrap(Internal.New()).Severity())
+	require.Equal(t, SeverityCritical, NotFound.Wrap(critical.New()).Severity())
+	require.Equal(t, SeverityCritical, critical.Derive(`child`).New().Severity())
+
+	b, err := json.Marshal(critical)
+	require.Nil(t, err)
+	require.Contains(t, string(b), `"severity":"critical"`)
+}
+
+type logger interface {
+	Log(msg string)
+}
+
+// declared at package level, since keys are registered globally and tests may run more than once
+var (
+	level     = NewExtKey[int](`test:level`)
+	loggerKey = NewExtKey[logger](`test:logger`)
+)
+
+func TestExtKey(t *testing.T) {
+	require.Panics(t, func() { NewExtKey[string](`test:level`) })
+
+	parent := level.Set(&Def{Code: `parent`}, 1)
+	child := parent.Derive(`child`)
+	v, ok := level.Get(child)
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	_, ok = loggerKey.Get(child)
+	require.False(t, ok)
+
+	require.Panics(t, func() { child.Extend(`test:level`, `not int`) })
+	require.Panics(t, func() { Extend(`test:level`, nil) })
+	require.NotPanics(t, func() { child.Extend(`test:logger`, nil) })
+	child.Extend(`test:level`, 2)
+	v, _ = level.Get(child)
+	require.Equal(t, 2, v)
+
+	m := Manager
+	defer func() { Manager = m }()
+	manager := Init(level.Option(3))
+	v, ok = level.FromManager(manager)
+	require.True(t, ok)
+	require.Equal(t, 3, v)
+	_, ok = loggerKey.FromManager(manager)
+	require.False(t, ok)
+	require.Equal(t, `github.com/EchoUtopia/zerror/v2`, callerPackage(1))
+}
+
+func TestRenderRelease(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	manager := Init(SetDebugMode(true))
+	h := NotFound.WithMsg(`first`).Render()
+	rsp := h.Render.(*StdResponse)
+	b, err := json.Marshal(h)
+	require.Nil(t, err)
+	require.Equal(t, `{"code":"zerror:not_found","msg":"zerror:not_found(first)"}`, string(b))
+	h.Release()
+	h.Release()
+	require.Equal(t, StdResponse{}, *rsp)
+
+	require.True(t, h.m == manager)
+
+	// renders of the previous manager are not used after Init
+	Init(WithRender(func() Render { return new(customeRsp) }, false))
+	h = BadRequest.New().Render()
+	require.IsType(t, &customeRsp{}, h.Render)
+	h.Release()
+	require.NotPanics(t, h.Release)
+}
+
+type customeDetailsRsp struct {
+	customeRsp
+	Details Data
+}
+
+func (c *customeDetailsRsp) SetDetails(details Data) {
+	c.Details = details
+}
+
+func TestPublicData(t *testing.T) {
+	invalid := (&Def{Code: `invalid`, Status: StatusBadRequest}).PublicKeys(`field`, `password`, `slice`)
+	child := invalid.Derive(`invalid_child`)
+	ze := Internal.Wrap(child.New().WithKVs(`field`, `name`, `password`, `p`, `sql`, `select`, `slice`, []int{1})).
+		WithKVs(`field`, `outer`)
+
+	rsp := ze.Unwrap().(*Error).Render().Render.(*StdResponse)
+	require.Equal(t, Data{`field`: `name`, `slice`: []int{1}}, rsp.Details)
+	rsp = ze.Render().Render.(*StdResponse)
+	require.Equal(t, Data{`field`: `name`, `slice`: []int{1}}, rsp.Details)
+	rsp = invalid.Wrap(ze).WithKVs(`field`, `public`).Render().Render.(*StdResponse)
+	require.Equal(t, Data{`field`: `public`, `slice`: []int{1}}, rsp.Details)
+	rsp = BadRequest.New().WithKVs(`field`, `name`).Render().Render.(*StdResponse)
+	require.Nil(t, rsp.Details)
+
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithRender(func() Render { return new(customeDetailsRsp) }, false))
+	custom := child.New().WithKVs(`field`, `name`).Render().Render.(*customeDetailsRsp)
+	require.Equal(t, Data{`field`: `name`}, custom.Details)
+}
+
+func TestProblemDetails(t *testing.T) {
+	m := Manager
+	defer func() { Manager = m }()
+	Init(WithRender(ProblemRender(`https://errors.example.com/`), true))
+
+	invalid := (&Def{Code: `user:invalid`, Status: StatusBadRequest, Msg: `invalid user {field}`}).PublicKeys(`field`)
+	ctx := WithInstance(context.Background(), `/users/1`)
+	h := invalid.WithMsg(`name too long`).WithKVs(`field`, `name`, `status`, `overridden`).WithCtx(ctx).Render()
+	require.Equal(t, ContentTypeProblem, h.Render.(ContentTyper).ContentType())
+	b, err := json.Marshal(h)
+	require.Nil(t, err)
+	require.JSONEq(t, `{
+		"type": "https://errors.example.com/user:invalid",
+		"title": "invalid user {field}",
+		"status": 400,
+		"detail": "user:invalid(name too long)",
+		"instance": "/users/1",
+		"code": "user:invalid",
+		"field": "name"
+	}`, string(b))
+	h.Release()
+
+	decoded := &ProblemDetails{}
+	require.Nil(t, json.Unmarshal(b, decoded))
+	require.Equal(t, `user:invalid`, decoded.Code)
+	require.Equal(t, 400, decoded.Status)
+	require.Equal(t, Data{`field`: `name`}, decoded.Extensions)
+
+	Init(WithRender(ProblemRender(``), false))
+	problem := invalid.New().WithKVs(`field`, `age`).Render().Render.(*ProblemDetails)
+	require.Equal(t, `invalid user {field}`, problem.Title)
+	require.Equal(t, `invalid user age`, problem.Detail)
+
+	h = BadRequest.Wrap(Internal.New()).Render()
+	b, err = json.Marshal(h)
+	require.Nil(t, err)
+	require.JSONEq(t, `{"type": "about:blank", "title": "internal error", "status": 500, "code": "zerror:internal"}`, string(b))
+}
+
+func TestFromRemote(t *testing.T) {
+	ze := FromRemote(NotFound.Code, StatusBadRequest, `user 1 not found`)
+	require.True(t, ze.Def == NotFound)
+	require.True(t, ze.Remote())
+	require.Equal(t, `zerror:not_found(user 1 not found)`, ze.Error())
+	_, name := ze.GetCaller()
+	require.Equal(t, `TestFromRemote`, name)
+	require.Contains(t, fmt.Sprintf(`%+v`, ze), `(remote)`)
+
+	unknown := FromRemote(`remote:unknown`, StatusUnavailable, ``)
+	require.Equal(t, StatusUnavailable, unknown.Status)
+	require.True(t, unknown.Def == FromRemote(`remote:unknown`, StatusUnavailable, ``).Def)
+	require.False(t, NotFound.New().Remote())
+
+	decoded := &Error{}
+	require.Nil(t, json.Unmarshal([]byte(`{"code":"remote:unknown"}`), decoded))
+	require.True(t, unknown.Def == decoded.Def)
+
+	defer func() {
+		remoteDefs.Range(func(k, _ interface{}) bool {
+			remoteDefs.Delete(k)
+			return true
+		})
+		remoteDefCount = 0
+	}()
+	for i := 0; i < maxRemoteDefs+10; i++ {
+		FromRemote(fmt.Sprintf(`remote:code%d`, i), StatusUnavailable, ``)
+	}
+	require.EqualValues(t, maxRemoteDefs, remoteDefCount)
+	overflowed := FromRemote(`remote:overflowed`, StatusUnavailable, ``)
+	require.Equal(t, `remote:overflowed`, overflowed.Code)
+	body := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(body, httptest.NewRequest(http.MethodGet, `/metrics`, nil))
+	require.NotContains(t, body.Body.String(), `remote:code`)
+	require.Contains(t, body.Body.String(), `zerror_errors_created_total{code="zerror:remote_unregistered",status="0",group=""}`)
+
+	full := NotFound.Wrapf(BadRequest.Wrap(errors.New(`original error`)), `user 1`).Error()
+	ze = FromRemote(NotFound.Code, StatusNotFound, full)
+	require.Equal(t, full, ze.Error())
+	require.Equal(t, `user 1`, ze.msg)
+	require.Equal(t, `zerror:not_found`, FromRemote(NotFound.Code, StatusNotFound, NotFound.Code).Error())
+	require.Equal(t, `zerror:not_foundx`, FromRemote(NotFound.Code, StatusNotFound, `zerror:not_foundx`).msg)
+}
@@ -1,9 +1,7 @@
 package custom_error
 
 import (
-	logrus_ze "github.com/EchoUtopia/zerror/examples/v2/logrus"
 	"github.com/EchoUtopia/zerror/v2"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -17,7 +15,7 @@ var (
 		Prefix: "",
 
 		// the code will be `args`
-		Args: (&zerror.Def{Code: ``, Status: 400, Msg: `args err`, Description: ``}).Extend(logrus_ze.ExtLogLvl, logrus.DebugLevel),
+		Args: &zerror.Def{Code: ``, Status: 400, Severity: zerror.SeverityDebug, Msg: `args err`, Description: ``},
 	}
 
 	SmsCode           = &zerror.Def{Code: `sms:code`, Status: 500, Msg: `sms code`, Description: ``}
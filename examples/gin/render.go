@@ -1,28 +1,38 @@
 package gin
 
 import (
-	"errors"
 	logrus_ze "github.com/EchoUtopia/zerror/examples/v2/logrus"
 	"github.com/EchoUtopia/zerror/v2"
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	ExtLogWhenRespond = `log_when_respond`
-)
+var ExtLogWhenRespond = zerror.NewExtKey[bool](`gin:log_when_respond`)
 
-// if the error is not wrapped or generated by zerror.Def, wrap the error with zerror.Internal
+// if the error is not wrapped or generated by zerror.Def, it's converted by zerror.From
 func JSON(c *gin.Context, err error) {
 	if !zerror.Manager.Registered() {
 		panic(`groups not registered`)
 	}
-	var zerr *zerror.Error
-	if ok := errors.As(err, &zerr); !ok {
-		zerr = zerror.Internal.Wrap(err)
-	}
-	c.JSON(int(zerr.Status), zerr.Render())
+	zerr := zerror.From(err)
+	rendered := zerr.Render()
+	c.JSON(int(zerr.Status), rendered)
+	rendered.Release()
 	c.Abort()
-	if _, logWhenRespond := zerror.Manager.GetExtension(ExtLogWhenRespond); logWhenRespond {
+	if logWhenRespond, _ := ExtLogWhenRespond.FromManager(zerror.Manager); logWhenRespond {
 		logrus_ze.LogCtx(c.Request.Context(), err)
 	}
 }
+
+// recover panics in handlers and respond them with JSON as zerror.Internal
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var err error
+		defer func() {
+			if err != nil {
+				JSON(c, err)
+			}
+		}()
+		defer zerror.Recover(&err)
+		c.Next()
+	}
+}
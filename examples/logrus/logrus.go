@@ -8,14 +8,17 @@ import (
 	"log"
 )
 
-const (
-	ExtLogLvl             = `log_level`
-	ExtLogger             = `logger`
-	ExtExtractDataFromCtx = `extract_from_ctx`
+var (
+	ExtLogger             = zerror.NewExtKey[logrus.FieldLogger](`logrus:logger`)
+	ExtExtractDataFromCtx = zerror.NewExtKey[ExtractDataFromCtx](`logrus:extract_from_ctx`)
 )
 
-func init(){
-	zerror.Internal.Extend(ExtLogLvl, logrus.ErrorLevel)
+var severityLevels = map[zerror.Severity]logrus.Level{
+	zerror.SeverityDebug:    logrus.DebugLevel,
+	zerror.SeverityInfo:     logrus.InfoLevel,
+	zerror.SeverityWarn:     logrus.WarnLevel,
+	zerror.SeverityError:    logrus.ErrorLevel,
+	zerror.SeverityCritical: logrus.FatalLevel,
 }
 
 type ExtractDataFromCtx func(context.Context) zerror.Data
@@ -28,18 +31,14 @@ func LogCtx(ctx context.Context, err error) {
 	l, n := ``, ``
 	if ok := errors.As(err, &zerr); ok {
 		l, n = zerr.GetCaller()
-		lli, ok := zerr.Def.GetExtension(ExtLogLvl)
-		if ok {
-			logLevel = lli.(logrus.Level)
-		}
-		data = zerr.Data
+		logLevel = severityLevels[zerr.Severity()]
+		data = zerr.RedactedData()
 	} else {
 		l, n = zerror.GetCaller(nil, 3)
 	}
-	extractor, ok := zerror.Manager.GetExtension(ExtExtractDataFromCtx)
+	extractor, ok := ExtExtractDataFromCtx.FromManager(zerror.Manager)
 	if ok {
-		f := extractor.(ExtractDataFromCtx)
-		for k, v := range f(ctx) {
+		for k, v := range extractor(ctx) {
 			data[k] = v
 		}
 	}
@@ -57,11 +56,8 @@ func Log(err error) {
 	zerr := &zerror.Error{}
 	if ok := errors.As(err, &zerr); ok {
 		l, n = zerr.GetCaller()
-		data = zerr.Data
-		lli, ok := zerr.Def.GetExtension(ExtLogLvl)
-		if ok {
-			logLevel = lli.(logrus.Level)
-		}
+		data = zerr.RedactedData()
+		logLevel = severityLevels[zerr.Severity()]
 	} else {
 		l, n = zerror.GetCaller(nil, 2)
 	}
@@ -74,11 +70,10 @@ func Log(err error) {
 
 func getAndLog(err error, data zerror.Data, level logrus.Level) {
 
-	iLogger, ok := zerror.Manager.GetExtension(ExtLogger)
-	logger, isLogger := iLogger.(logrus.FieldLogger)
-	if ! ok || ! isLogger {
+	logger, ok := ExtLogger.FromManager(zerror.Manager)
+	if !ok {
 		log.Printf(`you should extend for logger with statement: 
-		zerror.Extend(logrus_ze.ExtLogger, logger)`)
+		logrus_ze.ExtLogger.Option(logger)`)
 		logger = logrus.StandardLogger()
 	}
 	logger.WithFields(logrus.Fields(data)).WithError(err).Log(level)
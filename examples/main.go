@@ -53,15 +53,16 @@ func main() {
 	manager := zerror.Init(
 		// zerror.DebugMode(true),
 		zerror.DefaultStatus(zerror.StatusBadRequest),
-		zerror.Extend(logrus_ze.ExtLogger, logrus.StandardLogger()),
-		zerror.Extend(gin_ze.ExtLogWhenRespond, true),
-		zerror.Extend(logrus_ze.ExtExtractDataFromCtx, logrus_ze.ExtractDataFromCtx(ExtractFromCtx)),
+		logrus_ze.ExtLogger.Option(logrus.StandardLogger()),
+		gin_ze.ExtLogWhenRespond.Option(true),
+		logrus_ze.ExtExtractDataFromCtx.Option(ExtractFromCtx),
 	)
 
 	// error group must be registered
 	manager.RegisterGroups(custom_error.Common, custom_error.Auth)
 
 	r := gin.Default()
+	r.Use(gin_ze.Recovery())
 	r.Use(SetCtxValue())
 	r.GET(`/error`, HandleDefault)
 	r.GET(`/error/original`, HandleOriginal)
@@ -0,0 +1,111 @@
+package zerror
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// typed key of extensions of Def and Zmanager,
+// values are stored under the key name, so they are also visible to GetExtension
+type ExtKey[T any] struct {
+	name string
+}
+
+type extKeyInfo struct {
+	typ reflect.Type
+	pkg string
+}
+
+var extKeys = struct {
+	sync.Mutex
+	m map[string]extKeyInfo
+}{m: make(map[string]extKeyInfo)}
+
+// should be called in package level var declarations,
+// panics if the name is already used by another ExtKey, so collisions between packages are found at start up
+func NewExtKey[T any](name string) ExtKey[T] {
+	info := extKeyInfo{
+		typ: reflect.TypeOf((*T)(nil)).Elem(),
+		pkg: callerPackage(2),
+	}
+	extKeys.Lock()
+	defer extKeys.Unlock()
+	if existing, ok := extKeys.m[name]; ok {
+		log.Panicf(`extension key %q of type %s in package %s collides with the one of type %s in package %s`,
+			name, info.typ, info.pkg, existing.typ, existing.pkg)
+	}
+	extKeys.m[name] = info
+	return ExtKey[T]{name: name}
+}
+
+func (k ExtKey[T]) Name() string {
+	return k.name
+}
+
+func (k ExtKey[T]) Set(def *Def, v T) *Def {
+	return def.Extend(k.name, v)
+}
+
+// the value of def or its ancestors
+func (k ExtKey[T]) Get(def *Def) (T, bool) {
+	v, ok := def.GetExtension(k.name)
+	return castExt[T](v, ok)
+}
+
+// the option to set the extension of manager
+func (k ExtKey[T]) Option(v T) Option {
+	return Extend(k.name, v)
+}
+
+func (k ExtKey[T]) FromManager(m *Zmanager) (T, bool) {
+	v, ok := m.GetExtension(k.name)
+	return castExt[T](v, ok)
+}
+
+func castExt[T any](v interface{}, ok bool) (T, bool) {
+	if ok {
+		if t, ok := v.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// panics if key is registered by ExtKey with a type that v is not assignable to
+func checkExtValue(key string, v interface{}) {
+	extKeys.Lock()
+	info, ok := extKeys.m[key]
+	extKeys.Unlock()
+	if !ok || v == nil && isNillable(info.typ) {
+		return
+	}
+	if typ := reflect.TypeOf(v); typ == nil || !typ.AssignableTo(info.typ) {
+		panic(fmt.Sprintf(`extension key %q of package %s requires type %s, but got %T`, key, info.pkg, info.typ, v))
+	}
+}
+
+func isNillable(typ reflect.Type) bool {
+	switch typ.Kind() {
+	case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		return true
+	}
+	return false
+}
+
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return `unknown`
+	}
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndexByte(name, '/')
+	if dot := strings.IndexByte(name[slash+1:], '.'); dot >= 0 {
+		return name[:slash+1+dot]
+	}
+	return name
+}
@@ -0,0 +1,103 @@
+package zerror
+
+import (
+	"errors"
+	"hash/fnv"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hash of def codes and callers of the chain, messages and data are excluded,
+// so errors generated in the same function with the same defs share the fingerprint.
+// only function names and file names of callers are used, directories and lines are excluded,
+// so fingerprints are stable across deploys where code above the call sites changes,
+// but errors generated at different lines of the same function with the same defs are not told apart
+func (ze *Error) Fingerprint() string {
+	h := fnv.New64a()
+	for _, layer := range ze.layers() {
+		loc, name := layer.caller(true)
+		// loc is file/line
+		if i := strings.LastIndexByte(loc, '/'); i >= 0 {
+			loc = loc[:i]
+		}
+		h.Write([]byte(layer.Def.Code))
+		h.Write([]byte{0})
+		h.Write([]byte(name))
+		h.Write([]byte{0})
+		h.Write([]byte(filepath.Base(loc)))
+		h.Write([]byte{0})
+	}
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// suppress errors with the same fingerprint repeated in a time window
+type Deduper struct {
+	window    time.Duration
+	mu        sync.Mutex
+	seen      map[string]*dedupEntry
+	lastPrune time.Time
+}
+
+type dedupEntry struct {
+	reported   time.Time
+	suppressed int
+}
+
+func NewDeduper(window time.Duration) *Deduper {
+	return &Deduper{
+		window: window,
+		seen:   make(map[string]*dedupEntry),
+	}
+}
+
+// reports whether err should be reported, which is true for the first error of a fingerprint in a window,
+// suppressed is the count of errors suppressed since the last reported one of the same fingerprint,
+// the count is dropped if no error of the fingerprint is seen in the window after the suppressing one.
+// errors not generated by zerror are fingerprinted by their messages
+func (d *Deduper) Allow(err error) (ok bool, suppressed int) {
+	key := fingerprint(err)
+	now := time.Now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	entry, found := d.seen[key]
+	if found && now.Sub(entry.reported) < d.window {
+		entry.suppressed++
+		return false, 0
+	}
+	if found {
+		suppressed = entry.suppressed
+	}
+	d.prune(now)
+	d.seen[key] = &dedupEntry{reported: now}
+	return true, suppressed
+}
+
+func (d *Deduper) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < d.window {
+		return
+	}
+	d.lastPrune = now
+	for k, entry := range d.seen {
+		// keep suppressed counts one more window to report them
+		ttl := d.window
+		if entry.suppressed > 0 {
+			ttl *= 2
+		}
+		if now.Sub(entry.reported) >= ttl {
+			delete(d.seen, k)
+		}
+	}
+}
+
+func fingerprint(err error) string {
+	zerr := &Error{}
+	if ok := errors.As(err, &zerr); ok {
+		return zerr.Fingerprint()
+	}
+	h := fnv.New64a()
+	h.Write([]byte(err.Error()))
+	return `msg:` + strconv.FormatUint(h.Sum64(), 16)
+}
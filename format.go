@@ -0,0 +1,110 @@
+package zerror
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// %s, %v: same as Error()
+// %q: quoted Error()
+// %+v: every layer of the chain in its own line, with code, message, caller, data and stack,
+// wrappers not generated by zerror, like fmt.Errorf with %w, are written as their own texts,
+// the chain dump ends at the first cause not wrapping errors generated by zerror
+func (ze *Error) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			ze.writeVerbose(s)
+			return
+		}
+		io.WriteString(s, ze.Error())
+	case 's':
+		io.WriteString(s, ze.Error())
+	case 'q':
+		fmt.Fprintf(s, `%q`, ze.Error())
+	default:
+		fmt.Fprintf(s, `%%!%c(*zerror.Error=%s)`, verb, ze.Error())
+	}
+}
+
+func (ze *Error) writeVerbose(w io.Writer) {
+	b := strings.Builder{}
+	for layer := ze; ; {
+		layer.writeLayer(&b)
+		cause := layer.cause
+		if cause == nil {
+			break
+		}
+		next, ok := cause.(*Error)
+		if !ok {
+			// like layers, the chain goes on through wrappers not generated by zerror,
+			// their own texts are written in place
+			next = &Error{}
+			if !errors.As(cause, &next) {
+				b.WriteString(cause.Error())
+				b.WriteString("\n")
+				break
+			}
+			text := cause.Error()
+			if own := strings.TrimSuffix(text, next.Error()); own != text {
+				text = strings.TrimSpace(own)
+			}
+			if text != `` {
+				b.WriteString(text)
+				b.WriteString("\n")
+			}
+		}
+		layer = next
+	}
+	io.WriteString(w, strings.TrimSuffix(b.String(), "\n"))
+}
+
+func (ze *Error) writeLayer(b *strings.Builder) {
+	b.WriteString(ze.Def.Code)
+	if ze.msg != `` {
+		b.WriteString(`: `)
+		policy := ze.Def.redactPolicy()
+		if policy.redacted(policy.Msg, false) {
+			b.WriteString(policy.mask())
+		} else {
+			b.WriteString(ze.msg)
+		}
+	}
+	if ze.remote {
+		b.WriteString(` (remote)`)
+	}
+	b.WriteString("\n")
+	loc, name := ze.layerCaller()
+	if name != `` || loc != `` {
+		b.WriteString("\tcaller: ")
+		b.WriteString(name)
+		if loc != `` {
+			b.WriteString(` (`)
+			b.WriteString(loc)
+			b.WriteString(`)`)
+		}
+		b.WriteString("\n")
+	}
+	if len(ze.Data) > 0 {
+		b.WriteString("\tdata:")
+		data := ze.Def.redactPolicy().redactData(ze.Data, false)
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(b, ` %s=%v`, k, data[k])
+		}
+		b.WriteString("\n")
+	}
+	if ze.stack != nil {
+		b.WriteString("\tstack:\n")
+		for _, frame := range ze.stack.Frames() {
+			fmt.Fprintf(b, "\t\t%s\n\t\t\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+	}
+}
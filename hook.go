@@ -0,0 +1,98 @@
+package zerror
+
+import "context"
+
+// called when an error is generated, ctx is the context of the error, or context.Background() if not set.
+// hooks run before WithCtx can be called, so the context is only set for errors generated by
+// Def.NewCtx, Def.WithMsgCtx and Def.WrapCtx, or wrapping errors that have contexts.
+// the returned error replaces ze if not nil, so hooks can add data to it
+type Hook func(ctx context.Context, ze *Error) *Error
+
+// called when an error is rendered, after code and message are set
+type RenderHook func(ctx context.Context, ze *Error, r Render)
+
+type hooks struct {
+	create []Hook
+	wrap   []Hook
+	render []RenderHook
+}
+
+// called when errors are generated by Def.New, Def.NewCtx, Def.WithMsg, Def.WithMsgCtx, Def.Errorf and Recover
+func (m *Zmanager) OnCreate(h Hook) {
+	m.updateHooks(func(hs *hooks) {
+		hs.create = append(hs.create, h)
+	})
+}
+
+// called when errors are generated by Def.Wrap, Def.WrapCtx, Def.Wrapf and From
+func (m *Zmanager) OnWrap(h Hook) {
+	m.updateHooks(func(hs *hooks) {
+		hs.wrap = append(hs.wrap, h)
+	})
+}
+
+// called when errors are rendered by Error.Render
+func (m *Zmanager) OnRender(h RenderHook) {
+	m.updateHooks(func(hs *hooks) {
+		hs.render = append(hs.render, h)
+	})
+}
+
+// hooks are copied on write, so they can be read without lock
+func (m *Zmanager) updateHooks(update func(hs *hooks)) {
+	m.Lock()
+	defer m.Unlock()
+	hs := &hooks{}
+	if old := m.loadHooks(); old != nil {
+		hs.create = append(hs.create, old.create...)
+		hs.wrap = append(hs.wrap, old.wrap...)
+		hs.render = append(hs.render, old.render...)
+	}
+	update(hs)
+	m.hooks.Store(hs)
+}
+
+func (m *Zmanager) loadHooks() *hooks {
+	hs, _ := m.hooks.Load().(*hooks)
+	return hs
+}
+
+func (m *Zmanager) runCreateHooks(ze *Error) *Error {
+	if hs := m.loadHooks(); hs != nil {
+		return runHooks(hs.create, ze)
+	}
+	return ze
+}
+
+func (m *Zmanager) runWrapHooks(ze *Error) *Error {
+	if hs := m.loadHooks(); hs != nil {
+		return runHooks(hs.wrap, ze)
+	}
+	return ze
+}
+
+func (m *Zmanager) runRenderHooks(ze *Error, r Render) {
+	hs := m.loadHooks()
+	if hs == nil {
+		return
+	}
+	for _, h := range hs.render {
+		h(ze.context(), ze, r)
+	}
+}
+
+func runHooks(hs []Hook, ze *Error) *Error {
+	for _, h := range hs {
+		if replaced := h(ze.context(), ze); replaced != nil {
+			ze = replaced
+		}
+	}
+	return ze
+}
+
+func (ze *Error) context() context.Context {
+	if ze.Ctx == nil {
+		return context.Background()
+	}
+	return ze.Ctx
+}
@@ -0,0 +1,86 @@
+package zerror
+
+import (
+	"encoding/json"
+	"log"
+)
+
+// returns a child def of def with code,
+// the child inherits Status, Severity, Msg, Description and retry classification, which can be overridden,
+// and extensions of def are visible to the child unless it extends the same keys
+func (def *Def) Derive(code string) *Def {
+	child := &Def{Code: code, Parent: def}
+	child.inherit()
+	return child
+}
+
+// report whether def is d or an ancestor of d
+func (def *Def) covers(d *Def) bool {
+	found := false
+	d.lineage(func(a *Def) bool {
+		found = a == def
+		return !found
+	})
+	return found
+}
+
+// call f with def and its ancestors from near to far until f returns false,
+// the walk stops when parents are circular, which is detected like Floyd's algorithm without allocation,
+// so f may be called more than once with a def in the cycle
+func (def *Def) lineage(f func(d *Def) bool) {
+	slow := def
+	for d, i := def, 0; d != nil; i++ {
+		if !f(d) {
+			return
+		}
+		d = d.Parent
+		if i%2 == 1 {
+			slow = slow.Parent
+		}
+		if d == slow {
+			return
+		}
+	}
+}
+
+// fill the zero fields with those of ancestors, panics if parents are circular
+func (def *Def) inherit() {
+	visited := map[*Def]bool{def: true}
+	for p := def.Parent; p != nil; p = p.Parent {
+		if visited[p] {
+			log.Panicf(`def: %s has circular parents`, def.Code)
+		}
+		visited[p] = true
+		if def.Status == StatusInvalid {
+			def.Status = p.Status
+		}
+		if def.Severity == SeverityUnset {
+			def.Severity = p.Severity
+		}
+		if def.Msg == `` {
+			def.Msg = p.Msg
+		}
+		if def.Description == `` {
+			def.Description = p.Description
+		}
+		if def.Retry == RetryUnspecified {
+			def.Retry = p.Retry
+		}
+		if def.RetryAfter == 0 {
+			def.RetryAfter = p.RetryAfter
+		}
+	}
+}
+
+// the parent is encoded as its code
+func (def *Def) MarshalJSON() ([]byte, error) {
+	type plain Def
+	out := struct {
+		*plain
+		Parent string `json:"parent,omitempty"`
+	}{plain: (*plain)(def)}
+	if def.Parent != nil {
+		out.Parent = def.Parent.Code
+	}
+	return json.Marshal(out)
+}
@@ -0,0 +1,179 @@
+package zerror
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// json node of one layer of error chain,
+// the node of cause not generated by zerror has no code, only its whole message,
+// and the wrapped errors if any error generated by zerror is in them:
+// the cause of single wrappers like fmt.Errorf with %w, errs of *Multi and other multiple errors,
+// errs of *Multi have no message since it's joined by the errs
+type jsonError struct {
+	Code       string       `json:"code,omitempty"`
+	Status     Status       `json:"status,omitempty"`
+	Msg        string       `json:"msg,omitempty"`
+	Data       Data         `json:"data,omitempty"`
+	CallerLoc  string       `json:"caller_loc,omitempty"`
+	CallerName string       `json:"caller_name,omitempty"`
+	Cause      *jsonError   `json:"cause,omitempty"`
+	Errs       []*jsonError `json:"errs,omitempty"`
+}
+
+// encode every layer of the error chain, including layers wrapped by errors not generated by zerror,
+// like fmt.Errorf with %w and Multi, which are decoded as errors with the same messages and wrapped errors.
+// the chain ends at the first cause not wrapping errors generated by zerror, which is encoded as plain message,
+// context and stack trace are not encoded.
+// sensitive data and messages are redacted like Error() and RedactedData, so errors can be logged by json loggers,
+// use Unredacted to encode them losslessly
+func (ze *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ze.toJSON(true))
+}
+
+// json.Marshaler encoding the error like MarshalJSON without redaction,
+// for lossless transport between trusted parties, like job queues
+func (ze *Error) Unredacted() json.Marshaler {
+	return unredacted{ze}
+}
+
+type unredacted struct {
+	ze *Error
+}
+
+func (u unredacted) MarshalJSON() ([]byte, error) {
+	return json.Marshal(u.ze.toJSON(false))
+}
+
+// decode the error chain encoded by MarshalJSON, the decoded errors are marked as remote,
+// the defs are looked up in registered defs like FromCode,
+// if not found, the unregistered def of the code cached like FromRemote is used
+func (ze *Error) UnmarshalJSON(b []byte) error {
+	node := &jsonError{}
+	if err := json.Unmarshal(b, node); err != nil {
+		return err
+	}
+	if node.Code == `` {
+		return errors.New(`zerror: json has no error code`)
+	}
+	*ze = *node.toError().(*Error)
+	return nil
+}
+
+func (ze *Error) toJSON(redact bool) *jsonError {
+	loc, name := ze.layerCaller()
+	node := &jsonError{
+		Code:       ze.Def.Code,
+		Status:     ze.Def.Status,
+		Msg:        ze.msg,
+		Data:       ze.Data,
+		CallerLoc:  loc,
+		CallerName: name,
+	}
+	if redact {
+		policy := ze.Def.redactPolicy()
+		if node.Msg != `` && policy.redacted(policy.Msg, false) {
+			node.Msg = policy.mask()
+		}
+		if len(node.Data) > 0 {
+			node.Data = policy.redactData(node.Data, false)
+		}
+	}
+	if ze.cause != nil {
+		node.Cause = causeToJSON(ze.cause, redact)
+	}
+	return node
+}
+
+func causeToJSON(err error, redact bool) *jsonError {
+	switch e := err.(type) {
+	case *Error:
+		return e.toJSON(redact)
+	case *Multi:
+		node := &jsonError{}
+		for _, err := range e.errs {
+			node.Errs = append(node.Errs, causeToJSON(err, redact))
+		}
+		return node
+	}
+	node := &jsonError{Msg: err.Error()}
+	zerr := &Error{}
+	if !errors.As(err, &zerr) {
+		return node
+	}
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			node.Errs = append(node.Errs, causeToJSON(err, redact))
+		}
+	case interface{ Unwrap() error }:
+		node.Cause = causeToJSON(e.Unwrap(), redact)
+	}
+	return node
+}
+
+func (node *jsonError) toError() error {
+	if node.Code == `` {
+		return node.toForeign()
+	}
+	ze := &Error{
+		Def:    remoteDef(node.Code, node.Status),
+		msg:    node.Msg,
+		remote: true,
+		ZContext: ZContext{
+			callerLoc:  node.CallerLoc,
+			callerName: node.CallerName,
+			Data:       node.Data,
+		},
+	}
+	if node.Cause != nil {
+		ze.cause = node.Cause.toError()
+	}
+	return ze
+}
+
+func (node *jsonError) toForeign() error {
+	switch {
+	case node.Errs != nil:
+		errs := make([]error, 0, len(node.Errs))
+		for _, n := range node.Errs {
+			errs = append(errs, n.toError())
+		}
+		if node.Msg == `` {
+			return &Multi{errs: errs}
+		}
+		return &decodedJoin{msg: node.Msg, errs: errs}
+	case node.Cause != nil:
+		return &decodedWrapper{msg: node.Msg, err: node.Cause.toError()}
+	default:
+		return errors.New(node.Msg)
+	}
+}
+
+// decoded single wrapper not generated by zerror, like fmt.Errorf with %w
+type decodedWrapper struct {
+	msg string
+	err error
+}
+
+func (w *decodedWrapper) Error() string {
+	return w.msg
+}
+
+func (w *decodedWrapper) Unwrap() error {
+	return w.err
+}
+
+// decoded multiple errors not generated by zerror, like errors.Join
+type decodedJoin struct {
+	msg  string
+	errs []error
+}
+
+func (j *decodedJoin) Error() string {
+	return j.msg
+}
+
+func (j *decodedJoin) Unwrap() []error {
+	return j.errs
+}
@@ -0,0 +1,37 @@
+package zerror
+
+import "errors"
+
+// typed key of error data, the value is stored in Data under the key name,
+// so it's still visible to anything reading Data
+type Key[T any] struct {
+	name string
+}
+
+func NewKey[T any](name string) Key[T] {
+	return Key[T]{name: name}
+}
+
+func (k Key[T]) Name() string {
+	return k.name
+}
+
+// returns a copy of ze with the value added, like WithData
+func WithValue[T any](ze *Error, key Key[T], v T) *Error {
+	return ze.WithData(Data{key.name: v})
+}
+
+// search the value of key in the whole chain of err, from outer to inner layers,
+// values with a type other than T are ignored
+func Value[T any](err error, key Key[T]) (T, bool) {
+	zerr := &Error{}
+	if ok := errors.As(err, &zerr); ok {
+		for _, layer := range zerr.layers() {
+			if v, ok := layer.Data[key.name].(T); ok {
+				return v, true
+			}
+		}
+	}
+	var zero T
+	return zero, false
+}
@@ -0,0 +1,199 @@
+package zerror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// localized texts of a def
+type CatalogEntry struct {
+	Msg         string `json:"msg" yaml:"msg"`
+	Description string `json:"desc" yaml:"desc"`
+}
+
+// entries in files can also be a plain string as message
+func (e *CatalogEntry) UnmarshalJSON(b []byte) error {
+	var msg string
+	if err := json.Unmarshal(b, &msg); err == nil {
+		e.Msg = msg
+		return nil
+	}
+	type entry CatalogEntry
+	return json.Unmarshal(b, (*entry)(e))
+}
+
+func (e *CatalogEntry) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var msg string
+	if err := unmarshal(&msg); err == nil {
+		e.Msg = msg
+		return nil
+	}
+	type entry CatalogEntry
+	return unmarshal((*entry)(e))
+}
+
+// per-locale message catalog keyed by def code
+type Catalog struct {
+	mu sync.RWMutex
+	// locale -> code -> entry
+	entries map[string]map[string]CatalogEntry
+}
+
+func NewCatalog() *Catalog {
+	return &Catalog{entries: make(map[string]map[string]CatalogEntry)}
+}
+
+func (c *Catalog) Register(locale, code string, entry CatalogEntry) {
+	locale = normalizeLocale(locale)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries[locale] == nil {
+		c.entries[locale] = make(map[string]CatalogEntry)
+	}
+	c.entries[locale][code] = entry
+}
+
+// load entries of locale from json or yaml file (by extension .json, .yaml or .yml),
+// the file is an object of def code to entry or message
+func (c *Catalog) LoadFile(locale, path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	entries := map[string]CatalogEntry{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case `.json`:
+		err = json.Unmarshal(b, &entries)
+	case `.yaml`, `.yml`:
+		err = yaml.Unmarshal(b, &entries)
+	default:
+		err = fmt.Errorf(`zerror: unsupported catalog file extension: %s`, ext)
+	}
+	if err != nil {
+		return err
+	}
+	for code, entry := range entries {
+		c.Register(locale, code, entry)
+	}
+	return nil
+}
+
+// for every locale in order, the exact locale is tried first, then the base language,
+// e.g. `zh-CN` falls back to `zh`
+func (c *Catalog) Lookup(code string, locales ...string) (CatalogEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, locale := range locales {
+		locale = normalizeLocale(locale)
+		if entry, ok := c.entries[locale][code]; ok {
+			return entry, true
+		}
+		if i := strings.IndexByte(locale, '-'); i > 0 {
+			if entry, ok := c.entries[locale[:i]][code]; ok {
+				return entry, true
+			}
+		}
+	}
+	return CatalogEntry{}, false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), `_`, `-`))
+}
+
+type localeKey struct{}
+
+// store preferred locales in ctx, in order of preference
+func WithLocale(ctx context.Context, locales ...string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locales)
+}
+
+// store locales parsed from http Accept-Language header in ctx
+func WithAcceptLanguage(ctx context.Context, header string) context.Context {
+	return WithLocale(ctx, ParseAcceptLanguage(header)...)
+}
+
+func LocalesFromContext(ctx context.Context) []string {
+	if ctx == nil {
+		return nil
+	}
+	locales, _ := ctx.Value(localeKey{}).([]string)
+	return locales
+}
+
+// returns locales sorted by quality, `*` and locales with quality 0 are excluded
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var ws []weighted
+	for _, part := range strings.Split(header, `,`) {
+		fields := strings.Split(part, `;`)
+		locale := strings.TrimSpace(fields[0])
+		if locale == `` || locale == `*` {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, `q=`) {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			ws = append(ws, weighted{locale: locale, q: q})
+		}
+	}
+	sort.SliceStable(ws, func(i, j int) bool {
+		return ws[i].q > ws[j].q
+	})
+	out := make([]string, len(ws))
+	for i, w := range ws {
+		out[i] = w.locale
+	}
+	return out
+}
+
+// the localized entry of def, fallback to the manager's default locale,
+// then entries of ancestors whose messages def inherits, then the def's own texts
+func (def *Def) localize(locales []string) CatalogEntry {
+	catalog := Manager.catalog
+	if catalog == nil {
+		return CatalogEntry{Msg: def.Msg, Description: def.Description}
+	}
+	if Manager.defaultLocale != `` {
+		locales = append(locales[:len(locales):len(locales)], Manager.defaultLocale)
+	}
+	entry, found := CatalogEntry{}, false
+	def.lineage(func(d *Def) bool {
+		entry, found = catalog.Lookup(d.Code, locales...)
+		return !found && d.Parent != nil && d.Parent.Msg == def.Msg
+	})
+	if !found {
+		return CatalogEntry{Msg: def.Msg, Description: def.Description}
+	}
+	if entry.Description == `` {
+		entry.Description = def.Description
+	}
+	return entry
+}
+
+func (def *Def) LocalizedMsg(locales ...string) string {
+	return def.localize(locales).Msg
+}
+
+func (def *Def) LocalizedDescription(locales ...string) string {
+	return def.localize(locales).Description
+}
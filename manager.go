@@ -17,6 +17,11 @@ type Zmanager struct {
 	errGroups []interface{}
 	sync.Mutex
 	registered int32
+	// *hooks
+	hooks atomic.Value
+	// pool of *RenderHandle
+	pool     sync.Pool
+	poolOnce sync.Once
 }
 
 // the parameters must be error group ptr,
@@ -71,9 +76,13 @@ func initErrGroup(group interface{}) {
 			def = structField.Interface().(*Def)
 		}
 
+		def.inherit()
 		if def.Status == StatusInvalid {
 			def.Status = Manager.defaultStatus
 		}
+		if def.Severity == SeverityUnset {
+			def.Severity = StatusSeverity(def.Status)
+		}
 
 		if def.Code == `` {
 			def.Code = fmt.Sprintf(`%s%s`, prefix, getStandardName(tField.Name))
@@ -83,6 +92,7 @@ func initErrGroup(group interface{}) {
 		}
 		errCnt++
 		defMap[def.Code] = def
+		defGroups[def.Code] = groupName
 	}
 	if errCnt == 0 {
 		log.Panicf(`error def not found in group: %s`, group)
@@ -124,6 +134,8 @@ func (m *Zmanager) RegisterGroups(groups ...interface{}) {
 	if m.registered == 1 {
 		panic(`groups registered twice`)
 	}
+	defMu.Lock()
+	defer defMu.Unlock()
 	for _, v := range groups {
 		initErrGroup(v)
 		m.errGroups = append(m.errGroups, v)
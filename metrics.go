@@ -0,0 +1,110 @@
+package zerror
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type defCounter struct {
+	// status of the first def generating errors with the code
+	status   Status
+	created  uint64
+	rendered uint64
+}
+
+// code -> *defCounter, keyed by code so unregistered defs with the same code share one counter
+var counters sync.Map
+
+// the code label of errors of unregistered defs received from remote services,
+// they share one counter so remote services can't grow the label values without bound
+const CodeRemoteUnregistered = `zerror:remote_unregistered`
+
+func init() {
+	expvar.Publish(`zerror`, expvar.Func(func() interface{} {
+		return metricsSnapshot()
+	}))
+}
+
+func counterOf(def *Def) *defCounter {
+	code, status := def.Code, def.Status
+	if def.remote {
+		code, status = CodeRemoteUnregistered, StatusInvalid
+	}
+	if c, ok := counters.Load(code); ok {
+		return c.(*defCounter)
+	}
+	c, _ := counters.LoadOrStore(code, &defCounter{status: status})
+	return c.(*defCounter)
+}
+
+type DefMetric struct {
+	Code     string `json:"code"`
+	Status   Status `json:"status"`
+	Group    string `json:"group"`
+	Created  uint64 `json:"created"`
+	Rendered uint64 `json:"rendered"`
+}
+
+// metrics of all registered defs and codes of unregistered defs that have generated errors, sorted by code
+func metricsSnapshot() []DefMetric {
+	metrics := make(map[string]*DefMetric, len(defMap))
+	defMu.RLock()
+	for code, def := range defMap {
+		metrics[code] = &DefMetric{Code: code, Status: def.Status, Group: defGroups[code]}
+	}
+	defMu.RUnlock()
+	counters.Range(func(k, v interface{}) bool {
+		c := v.(*defCounter)
+		m, ok := metrics[k.(string)]
+		if !ok {
+			m = &DefMetric{Code: k.(string), Status: c.status}
+			metrics[m.Code] = m
+		}
+		m.Created = atomic.LoadUint64(&c.created)
+		m.Rendered = atomic.LoadUint64(&c.rendered)
+		return true
+	})
+	out := make([]DefMetric, 0, len(metrics))
+	for _, m := range metrics {
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Code < out[j].Code
+	})
+	return out
+}
+
+// serve error counters in prometheus text exposition format,
+// the counters are also published with expvar under name `zerror`
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(`Content-Type`, `text/plain; version=0.0.4; charset=utf-8`)
+		metrics := metricsSnapshot()
+		b := strings.Builder{}
+		writeCounter(&b, `zerror_errors_created_total`, `Errors generated by zerror defs.`, metrics,
+			func(m DefMetric) uint64 { return m.Created })
+		writeCounter(&b, `zerror_errors_rendered_total`, `Errors rendered by zerror defs.`, metrics,
+			func(m DefMetric) uint64 { return m.Rendered })
+		w.Write([]byte(b.String()))
+	})
+}
+
+func writeCounter(b *strings.Builder, name, help string, metrics []DefMetric, value func(DefMetric) uint64) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+	for _, m := range metrics {
+		fmt.Fprintf(b, "%s{code=\"%s\",status=\"%s\",group=\"%s\"} %d\n",
+			name, escapeLabel(m.Code), strconv.Itoa(int(m.Status)), escapeLabel(m.Group), value(m))
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
@@ -0,0 +1,115 @@
+package zerror
+
+import (
+	"errors"
+	"strings"
+)
+
+// select the error that represents all the errors of Multi,
+// errs is not empty
+type MultiPolicy func(errs []*Error) *Error
+
+// the error with the most severe status wins: 5xx > 4xx > others,
+// the first one wins if severities are equal
+func MostSevere(errs []*Error) *Error {
+	out := errs[0]
+	for _, ze := range errs[1:] {
+		if ze.Status/100 > out.Status/100 {
+			out = ze
+		}
+	}
+	return out
+}
+
+// the first error wins
+func FirstError(errs []*Error) *Error {
+	return errs[0]
+}
+
+// Multi holds several errors, like errors generated by batch operations or fan-out calls
+type Multi struct {
+	errs []error
+}
+
+// nil errors are discarded, returns nil if all errors are nil
+func Join(errs ...error) error {
+	m := &Multi{}
+	for _, err := range errs {
+		if err != nil {
+			m.errs = append(m.errs, err)
+		}
+	}
+	if len(m.errs) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *Multi) Error() string {
+	b := strings.Builder{}
+	for i, err := range m.errs {
+		if i != 0 {
+			b.WriteString(`; `)
+		}
+		b.WriteString(err.Error())
+	}
+	return b.String()
+}
+
+func (m *Multi) Unwrap() []error {
+	return m.errs
+}
+
+func (m *Multi) Errors() []error {
+	return m.errs
+}
+
+// return the error selected by the manager's multi policy,
+// nil if no error is generated by zerror
+func (m *Multi) Primary() *Error {
+	var zerrs []*Error
+	for _, err := range m.errs {
+		zerr := &Error{}
+		if ok := errors.As(err, &zerr); ok {
+			zerrs = append(zerrs, zerr)
+		}
+	}
+	if len(zerrs) == 0 {
+		return nil
+	}
+	policy := Manager.multiPolicy
+	if policy == nil {
+		policy = MostSevere
+	}
+	return policy(zerrs)
+}
+
+// so errors.As(multi, &zerr) finds the primary error
+func (m *Multi) As(target interface{}) bool {
+	p, ok := target.(**Error)
+	if !ok {
+		return false
+	}
+	primary := m.Primary()
+	if primary == nil {
+		return false
+	}
+	*p = primary
+	return true
+}
+
+// status of the primary error, StatusInternal if no error is generated by zerror
+func (m *Multi) Status() Status {
+	if primary := m.Primary(); primary != nil {
+		return primary.Status
+	}
+	return StatusInternal
+}
+
+// render the primary error, if no error is generated by zerror, render as Internal
+func (m *Multi) Render() *RenderHandle {
+	if primary := m.Primary(); primary != nil {
+		return primary.Render()
+	}
+	return Internal.Wrap(m).Render()
+}
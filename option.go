@@ -1,14 +1,24 @@
 package zerror
 
+import "strings"
+
 type Options struct {
-	wordConnector  string
-	codeConnector  string
-	respondMessage bool
-	respondMsgSet  bool
-	render         func() Render
-	defaultStatus  Status
-	debugMode      bool
-	extensions     map[string]interface{}
+	wordConnector    string
+	codeConnector    string
+	respondMessage   bool
+	respondMsgSet    bool
+	render           func() Render
+	defaultStatus    Status
+	debugMode        bool
+	extensions       map[string]interface{}
+	stackDepth       int
+	stackCapture     func(def *Def) bool
+	multiPolicy      MultiPolicy
+	redactPolicy     *RedactPolicy
+	classifiers      []Classifier
+	catalog          *Catalog
+	defaultLocale    string
+	missingKeyPolicy MissingKeyPolicy
 }
 
 type Option func(*Options)
@@ -36,7 +46,63 @@ func SetDebugMode(debug bool) Option {
 	}
 }
 
+// capture at most depth frames of stack trace for errors generated by defs that capture returns true,
+// if capture is nil, all defs capture stack trace, depth <= 0 disables it
+func WithStackTrace(depth int, capture func(def *Def) bool) Option {
+	return func(options *Options) {
+		options.stackDepth = depth
+		options.stackCapture = capture
+	}
+}
+
+// the policy to select the error representing Multi, when rendering or getting status,
+// default MostSevere
+func WithMultiPolicy(policy MultiPolicy) Option {
+	return func(options *Options) {
+		options.multiPolicy = policy
+	}
+}
+
+// if not set, DefaultRedactPolicy is used,
+// the policy can be overridden by def with extension ExtRedactPolicy
+func WithRedactPolicy(policy RedactPolicy) Option {
+	return func(options *Options) {
+		keys := make(map[string]Sensitivity, len(policy.Keys))
+		for k, v := range policy.Keys {
+			keys[strings.ToLower(k)] = v
+		}
+		policy.Keys = keys
+		options.redactPolicy = &policy
+	}
+}
+
+// classifiers used by From to convert errors not generated by zerror,
+// they are applied in order before the built-in ones
+func WithClassifiers(classifiers ...Classifier) Option {
+	return func(options *Options) {
+		options.classifiers = append(options.classifiers, classifiers...)
+	}
+}
+
+// localize messages with catalog, defaultLocale is used if no locale in context matched.
+// when catalog is set and error string is not responded, Render responds the localized message
+func WithCatalog(catalog *Catalog, defaultLocale string) Option {
+	return func(options *Options) {
+		options.catalog = catalog
+		options.defaultLocale = defaultLocale
+	}
+}
+
+// how to render placeholders of def messages whose keys are not in data, default MissingKeyKeep
+func WithMissingKeyPolicy(policy MissingKeyPolicy) Option {
+	return func(options *Options) {
+		options.missingKeyPolicy = policy
+	}
+}
+
+// panics if key is registered by ExtKey with another type, use ExtKey.Option for type safety
 func Extend(key string, value interface{}) Option {
+	checkExtValue(key, value)
 	return func(options *Options) {
 		if options.extensions == nil {
 			options.extensions = make(map[string]interface{})
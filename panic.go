@@ -0,0 +1,89 @@
+package zerror
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// the key of panic value in Data of errors generated by Recover
+var PanicValue = NewKey[interface{}](`panic`)
+
+const panicStackDepth = 64
+
+// convert panic to zerror.Internal error and set it to *errp, must be called with defer directly:
+//
+//	defer zerror.Recover(&err)
+//
+// the error carries the panic value, stack of the panicking goroutine and the panic location as caller,
+// if panic value is error, it's the cause of the error
+func Recover(errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*errp = Manager.runCreateHooks(panicError(r))
+}
+
+// run fn in a new goroutine, the returned channel receives the error returned by fn,
+// or the error converted from panic by Recover
+func SafeGo(fn func() error) <-chan error {
+	ch := make(chan error, 1)
+	go func() {
+		var err error
+		defer func() {
+			ch <- err
+		}()
+		defer Recover(&err)
+		err = fn()
+	}()
+	return ch
+}
+
+func panicError(r interface{}) *Error {
+	cause, ok := r.(error)
+	if !ok {
+		cause = errors.New(fmt.Sprint(r))
+	}
+	depth := panicStackDepth
+	if Manager.stackDepth > depth {
+		depth = Manager.stackDepth
+	}
+	stack := panicStack(depth)
+	atomic.AddUint64(&counterOf(Internal).created, 1)
+	ze := &Error{
+		cause: cause,
+		Def:   Internal,
+		msg:   `panic`,
+		ZContext: ZContext{
+			Data: Data{PanicValue.Name(): r},
+		},
+		stack: stack,
+	}
+	if len(stack) > 0 {
+		ze.callerPC = stack[0]
+	}
+	return ze
+}
+
+// the stack from the frame that panicked, runtime frames raising the panic are skipped
+func panicStack(depth int) StackTrace {
+	stack := callers(2, depth)
+	for i, pc := range stack {
+		if f := runtime.FuncForPC(pc - 1); f == nil || f.Name() != `runtime.gopanic` {
+			continue
+		}
+		stack = stack[i+1:]
+		for len(stack) > 1 {
+			f := runtime.FuncForPC(stack[0] - 1)
+			if f == nil || !strings.HasPrefix(f.Name(), `runtime.`) {
+				break
+			}
+			stack = stack[1:]
+		}
+		return stack
+	}
+	return stack
+}
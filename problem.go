@@ -0,0 +1,153 @@
+package zerror
+
+import (
+	"context"
+	"encoding/json"
+)
+
+const ContentTypeProblem = `application/problem+json`
+
+// renders implementing ContentTyper tell the content type of responses to framework adapters,
+// application/json is used if not implemented
+type ContentTyper interface {
+	ContentType() string
+}
+
+// renders implementing ErrorRender receive the error after code, message and details are set,
+// def is the def rendered, which is Internal if the error is caused by Internal
+type ErrorRender interface {
+	Render
+	RenderError(ze *Error, def *Def)
+}
+
+type instanceKey struct{}
+
+// store the problem instance, like the request path, in ctx
+func WithInstance(ctx context.Context, instance string) context.Context {
+	return context.WithValue(ctx, instanceKey{}, instance)
+}
+
+func instanceFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ``
+	}
+	instance, _ := ctx.Value(instanceKey{}).(string)
+	return instance
+}
+
+// RFC 9457 (obsoletes RFC 7807) problem details, responded as application/problem+json:
+// type is def code appended to the type base, title is def message, detail is the message of render,
+// instance is set by WithInstance in the error's context,
+// def code and public data are extension members
+type ProblemDetails struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Code       string
+	Extensions Data
+
+	typeBase string
+}
+
+// returns the render constructor for WithRender, type is typeBase + def code,
+// or `about:blank` if typeBase is empty
+func ProblemRender(typeBase string) func() Render {
+	return func() Render {
+		return &ProblemDetails{typeBase: typeBase}
+	}
+}
+
+func (p *ProblemDetails) SetCode(code string) {
+	p.Code = code
+	if p.typeBase == `` {
+		p.Type = `about:blank`
+	} else {
+		p.Type = p.typeBase + code
+	}
+}
+
+func (p *ProblemDetails) SetMessage(msg string) {
+	p.Detail = msg
+}
+
+func (p *ProblemDetails) SetDetails(details Data) {
+	p.Extensions = details
+}
+
+func (p *ProblemDetails) RenderError(ze *Error, def *Def) {
+	p.Status = int(def.Status)
+	if def.Status == StatusInvalid {
+		p.Status = int(StatusInternal)
+	}
+	// the title is the same for all occurrences of the type, so placeholders are kept unfilled,
+	// the filled message is the detail if no message is rendered
+	tmpl := def.LocalizedMsg(LocalesFromContext(ze.Ctx)...)
+	p.Title = fillTemplate(tmpl, nil, MissingKeyKeep)
+	if p.Detail == `` && hasPlaceholder(tmpl) {
+		p.Detail = ze.messageOf(def, true)
+	}
+	p.Instance = instanceFromContext(ze.Ctx)
+}
+
+func (p *ProblemDetails) Error() string {
+	return p.Detail
+}
+
+func (p *ProblemDetails) ContentType() string {
+	return ContentTypeProblem
+}
+
+func (p *ProblemDetails) Reset() {
+	*p = ProblemDetails{typeBase: p.typeBase}
+}
+
+var problemMembers = []string{`type`, `title`, `status`, `detail`, `instance`, `code`}
+
+// extension members never override standard members
+func (p *ProblemDetails) MarshalJSON() ([]byte, error) {
+	out := make(map[string]interface{}, len(problemMembers)+len(p.Extensions))
+	for k, v := range p.Extensions {
+		out[k] = v
+	}
+	for i, v := range []interface{}{p.Type, p.Title, p.Status, p.Detail, p.Instance, p.Code} {
+		if v != `` && v != 0 {
+			out[problemMembers[i]] = v
+		} else {
+			delete(out, problemMembers[i])
+		}
+	}
+	return json.Marshal(out)
+}
+
+// members other than standard ones and code are decoded to Extensions
+func (p *ProblemDetails) UnmarshalJSON(b []byte) error {
+	members := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &members); err != nil {
+		return err
+	}
+	targets := []interface{}{&p.Type, &p.Title, &p.Status, &p.Detail, &p.Instance, &p.Code}
+	for i, name := range problemMembers {
+		raw, ok := members[name]
+		if !ok {
+			continue
+		}
+		delete(members, name)
+		if err := json.Unmarshal(raw, targets[i]); err != nil {
+			return err
+		}
+	}
+	p.Extensions = nil
+	for k, raw := range members {
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return err
+		}
+		if p.Extensions == nil {
+			p.Extensions = make(Data, len(members))
+		}
+		p.Extensions[k] = v
+	}
+	return nil
+}
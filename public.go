@@ -0,0 +1,47 @@
+package zerror
+
+// extension key of def to declare data keys responded to clients
+var ExtPublicData = NewExtKey[[]string](`zerror:public_data`)
+
+// declare data keys that are responded to clients by Render, only data added to errors generated by def
+// or its descendants is responded, renders implementing DetailsRender receive them, other data stays server-side
+func (def *Def) PublicKeys(keys ...string) *Def {
+	return ExtPublicData.Set(def, keys)
+}
+
+// data of every layer whose keys are declared public by the layer's def or its ancestors,
+// so private data of a layer is never responded even if another def of the chain declares the key public.
+// values redacted for rendering are excluded
+func (ze *Error) publicData() Data {
+	layers := ze.layers()
+	var out Data
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		if len(layer.Data) == 0 {
+			continue
+		}
+		keys, ok := ExtPublicData.Get(layer.Def)
+		if !ok {
+			continue
+		}
+		policy := layer.Def.redactPolicy()
+		for _, k := range keys {
+			v, ok := layer.Data[k]
+			if !ok {
+				continue
+			}
+			if out == nil {
+				out = make(Data, len(keys))
+			}
+			if policy.keyRedacted(k, true) {
+				delete(out, k)
+			} else {
+				out[k] = v
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package zerror
+
+import "strings"
+
+// classes of sensitive data, can be combined
+type Sensitivity uint8
+
+const Insensitive Sensitivity = 0
+
+const (
+	// personally identifiable information
+	SensitivePII Sensitivity = 1 << iota
+	// passwords, tokens, keys and so on
+	SensitiveSecret
+	// information can be logged but should not be responded to clients
+	SensitiveInternal
+)
+
+// extension key of def to override the manager's RedactPolicy
+var ExtRedactPolicy = NewExtKey[RedactPolicy](`zerror:redact_policy`)
+
+const defaultMask = `[REDACTED]`
+
+type RedactPolicy struct {
+	// sensitivity of data keys, keys are case insensitive
+	Keys map[string]Sensitivity
+	// sensitivity of the messages of errors, like messages of Wrapf and WithMsg
+	Msg Sensitivity
+	// sensitivities redacted everywhere: Error(), Render, %+v and loggers using RedactedData
+	Redact Sensitivity
+	// sensitivities redacted additionally when rendering to clients
+	RenderRedact Sensitivity
+	// replacement of redacted values, default `[REDACTED]`
+	Mask string
+}
+
+// used if the manager has no redact policy
+var DefaultRedactPolicy = RedactPolicy{
+	Keys: map[string]Sensitivity{
+		`password`:      SensitiveSecret,
+		`secret`:        SensitiveSecret,
+		`token`:         SensitiveSecret,
+		`authorization`: SensitiveSecret,
+	},
+	Redact:       SensitiveSecret | SensitivePII,
+	RenderRedact: SensitiveInternal,
+}
+
+// the manager's policy overridden by the def's ExtRedactPolicy extension:
+// keys are merged, Msg is replaced, Redact, RenderRedact and Mask are replaced if not zero
+func (def *Def) redactPolicy() *RedactPolicy {
+	base := Manager.redactPolicy
+	if base == nil {
+		base = &DefaultRedactPolicy
+	}
+	override, ok := ExtRedactPolicy.Get(def)
+	if !ok {
+		return base
+	}
+	out := *base
+	out.Msg = override.Msg
+	if len(override.Keys) > 0 {
+		out.Keys = make(map[string]Sensitivity, len(base.Keys)+len(override.Keys))
+		for k, v := range base.Keys {
+			out.Keys[k] = v
+		}
+		for k, v := range override.Keys {
+			out.Keys[strings.ToLower(k)] = v
+		}
+	}
+	if override.Redact != Insensitive {
+		out.Redact = override.Redact
+	}
+	if override.RenderRedact != Insensitive {
+		out.RenderRedact = override.RenderRedact
+	}
+	if override.Mask != `` {
+		out.Mask = override.Mask
+	}
+	return &out
+}
+
+func (p *RedactPolicy) redacted(s Sensitivity, render bool) bool {
+	redact := p.Redact
+	if render {
+		redact |= p.RenderRedact
+	}
+	return s&redact != 0
+}
+
+func (p *RedactPolicy) mask() string {
+	if p.Mask == `` {
+		return defaultMask
+	}
+	return p.Mask
+}
+
+func (p *RedactPolicy) keyRedacted(key string, render bool) bool {
+	return p.redacted(p.Keys[strings.ToLower(key)], render)
+}
+
+func (p *RedactPolicy) redactData(data Data, render bool) Data {
+	out := make(Data, len(data))
+	for k, v := range data {
+		if p.keyRedacted(k, render) {
+			v = p.mask()
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// same as AllData, but values of sensitive keys are redacted,
+// loggers should use it instead of AllData
+func (ze *Error) RedactedData() Data {
+	return ze.redactedData(false)
+}
+
+func (ze *Error) redactedData(render bool) Data {
+	layers := ze.layers()
+	out := make(Data)
+	for i := len(layers) - 1; i >= 0; i-- {
+		layer := layers[i]
+		if len(layer.Data) == 0 {
+			continue
+		}
+		for k, v := range layer.Def.redactPolicy().redactData(layer.Data, render) {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package zerror
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// the max count of cached unregistered defs of remote codes,
+// codes are read from remote responses, so the cache is capped against misbehaving services
+const maxRemoteDefs = 1024
+
+var (
+	// code -> *Def, unregistered defs of codes received from remote services,
+	// cached so every code has only one def until the cache is full
+	remoteDefs     sync.Map
+	remoteDefCount int64
+)
+
+// the registered def of code, or the cached unregistered def with code and status,
+// if the cache is full, a new def is returned, which doesn't match other defs of the code with errors.Is
+func remoteDef(code string, status Status) *Def {
+	if def, ok := lookupDef(code); ok {
+		return def
+	}
+	if def, ok := remoteDefs.Load(code); ok {
+		return def.(*Def)
+	}
+	def := &Def{Code: code, Status: status, remote: true}
+	if atomic.AddInt64(&remoteDefCount, 1) > maxRemoteDefs {
+		atomic.AddInt64(&remoteDefCount, -1)
+		return def
+	}
+	actual, loaded := remoteDefs.LoadOrStore(code, def)
+	if loaded {
+		atomic.AddInt64(&remoteDefCount, -1)
+	}
+	return actual.(*Def)
+}
+
+// error received from a remote service, like responses of other services,
+// the def is looked up by code like FromCode, if not found, an unregistered def with code and status is used.
+// msg is the remote message, and the error is marked as remote.
+// if msg is the whole Error() of the remote error, like messages rendered in debug mode,
+// the code is stripped from msg, and the chain after the first layer becomes the cause
+func FromRemote(code string, status Status, msg string) *Error {
+	msg, chain := splitRemoteMsg(code, msg)
+	var cause error
+	if chain != `` {
+		cause = errors.New(chain)
+	}
+	ze := remoteDef(code, status).wrapf(cause, 3, ``)
+	ze.msg = msg
+	ze.remote = true
+	return Manager.runCreateHooks(ze)
+}
+
+// split `code(msg) | chain` or `code | chain` into msg and chain,
+// msg is returned as is if it doesn't start with the code
+func splitRemoteMsg(code, msg string) (string, string) {
+	rest, ok := strings.CutPrefix(msg, code)
+	if !ok {
+		return msg, ``
+	}
+	switch {
+	case rest == ``:
+		return ``, ``
+	case strings.HasPrefix(rest, ` | `):
+		return ``, rest[len(` | `):]
+	case rest[0] != '(':
+		return msg, ``
+	}
+	if i := strings.Index(rest, `) | `); i >= 0 {
+		return rest[1:i], rest[i+len(`) | `):]
+	}
+	if strings.HasSuffix(rest, `)`) {
+		return rest[1 : len(rest)-1], ``
+	}
+	return msg, ``
+}
+
+// report whether the error is received from a remote service
+func (ze *Error) Remote() bool {
+	return ze.remote
+}
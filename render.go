@@ -1,5 +1,11 @@
 package zerror
 
+import (
+	"encoding/json"
+	"sync"
+)
+
+// renders implementing Resetter are reused after released
 type Resetter interface {
 	Reset()
 }
@@ -9,9 +15,16 @@ type Render interface {
 	Error() string
 }
 
+// renders implementing DetailsRender receive public data declared by Def.PublicKeys
+type DetailsRender interface {
+	Render
+	SetDetails(details Data)
+}
+
 type StdResponse struct {
-	Code string `json:"code"`
-	Msg  string `json:"msg"`
+	Code    string `json:"code"`
+	Msg     string `json:"msg"`
+	Details Data   `json:"details,omitempty"`
 }
 
 func (r *StdResponse) SetCode(code string) {
@@ -22,6 +35,62 @@ func (r *StdResponse) SetMessage(msg string) {
 	r.Msg = msg
 }
 
+func (r *StdResponse) SetDetails(details Data) {
+	r.Details = details
+}
+
 func (r *StdResponse) Error() string {
 	return r.Msg
 }
+
+func (r *StdResponse) Reset() {
+	*r = StdResponse{}
+}
+
+// returned by Error.Render, wraps the render got from the pool of the manager,
+// it's encoded to json as the render
+type RenderHandle struct {
+	Render
+	m        *Zmanager
+	released bool
+}
+
+func (h *RenderHandle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Render)
+}
+
+// put the render back to the pool of the manager it's got from, if it implements Resetter,
+// the handle and the render must not be used after released
+func (h *RenderHandle) Release() {
+	if h.released {
+		return
+	}
+	h.released = true
+	reset, ok := h.Render.(Resetter)
+	if !ok {
+		return
+	}
+	reset.Reset()
+	h.m.renderPool().Put(h)
+}
+
+func (m *Zmanager) renderPool() *sync.Pool {
+	m.poolOnce.Do(func() {
+		m.pool.New = func() interface{} {
+			render := m.render
+			if render == nil {
+				render = func() Render {
+					return new(StdResponse)
+				}
+			}
+			return &RenderHandle{Render: render(), m: m}
+		}
+	})
+	return &m.pool
+}
+
+func (m *Zmanager) getRender() *RenderHandle {
+	h := m.renderPool().Get().(*RenderHandle)
+	h.released = false
+	return h
+}
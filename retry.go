@@ -0,0 +1,126 @@
+package zerror
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+type Retryability int8
+
+const (
+	RetryUnspecified Retryability = iota
+	Retryable
+	NonRetryable
+)
+
+var retryabilityNames = map[Retryability]string{
+	RetryUnspecified: `unspecified`,
+	Retryable:        `retryable`,
+	NonRetryable:     `non_retryable`,
+}
+
+func (r Retryability) String() string {
+	return retryabilityNames[r]
+}
+
+func (r Retryability) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Retryability) UnmarshalText(b []byte) error {
+	for k, v := range retryabilityNames {
+		if v == string(b) {
+			*r = k
+			return nil
+		}
+	}
+	return errors.New(`zerror: invalid retryability: ` + string(b))
+}
+
+// the outermost layer of the chain that is classified decides,
+// errors not classified are not retryable
+func IsRetryable(err error) bool {
+	zerr := &Error{}
+	if ok := errors.As(err, &zerr); !ok {
+		return false
+	}
+	for _, layer := range zerr.layers() {
+		switch {
+		case layer.Def.Retry == NonRetryable:
+			return false
+		case layer.Def.Retry == Retryable, layer.Def.RetryAfter > 0:
+			return true
+		}
+	}
+	return false
+}
+
+// the retry-after duration of the outermost layer that has one
+func RetryAfter(err error) (time.Duration, bool) {
+	zerr := &Error{}
+	if ok := errors.As(err, &zerr); !ok {
+		return 0, false
+	}
+	for _, layer := range zerr.layers() {
+		if layer.Def.RetryAfter > 0 {
+			return layer.Def.RetryAfter, true
+		}
+	}
+	return 0, false
+}
+
+type RetryPolicy struct {
+	// including the first attempt
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// the backoff is multiplied by Multiplier after every attempt
+	Multiplier float64
+	// the backoff is randomized in range [backoff*(1-Jitter), backoff*(1+Jitter)]
+	Jitter float64
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts:    3,
+	InitialBackoff: 100 * time.Millisecond,
+	MaxBackoff:     10 * time.Second,
+	Multiplier:     2,
+	Jitter:         0.2,
+}
+
+// call fn until it succeeds, returns error not retryable, or attempts are exhausted,
+// waits with exponential backoff and jitter between attempts, at least the RetryAfter of the error.
+// if ctx is done while waiting, the last error is returned
+func Retry(ctx context.Context, policy RetryPolicy, fn func(ctx context.Context) error) error {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || !IsRetryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+		wait := policy.jitter(backoff)
+		if after, ok := RetryAfter(err); ok && after > wait {
+			wait = after
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+func (p RetryPolicy) jitter(d time.Duration) time.Duration {
+	if p.Jitter <= 0 {
+		return d
+	}
+	return time.Duration(float64(d) * (1 + p.Jitter*(rand.Float64()*2-1)))
+}
@@ -0,0 +1,72 @@
+package zerror
+
+import "errors"
+
+type Severity int8
+
+const (
+	SeverityUnset Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarn
+	SeverityError
+	SeverityCritical
+)
+
+var severityNames = map[Severity]string{
+	SeverityUnset:    `unset`,
+	SeverityDebug:    `debug`,
+	SeverityInfo:     `info`,
+	SeverityWarn:     `warn`,
+	SeverityError:    `error`,
+	SeverityCritical: `critical`,
+}
+
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(b []byte) error {
+	for k, v := range severityNames {
+		if v == string(b) {
+			*s = k
+			return nil
+		}
+	}
+	return errors.New(`zerror: invalid severity: ` + string(b))
+}
+
+// default severity of status class: 5xx is error, 4xx is info, others are debug
+func StatusSeverity(status Status) Severity {
+	switch {
+	case status >= 500:
+		return SeverityError
+	case status >= 400:
+		return SeverityInfo
+	default:
+		return SeverityDebug
+	}
+}
+
+// the severity of def, or the default of its status if not set
+func (def *Def) severity() Severity {
+	if def.Severity != SeverityUnset {
+		return def.Severity
+	}
+	return StatusSeverity(def.Status)
+}
+
+// the maximum severity of all layers of the chain
+func (ze *Error) Severity() Severity {
+	out := SeverityUnset
+	for _, layer := range ze.layers() {
+		if s := layer.Def.severity(); s > out {
+			out = s
+		}
+	}
+	return out
+}
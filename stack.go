@@ -0,0 +1,61 @@
+package zerror
+
+import (
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// the program counters captured when error is created,
+// they are symbolized lazily when Frames or String is called
+type StackTrace []uintptr
+
+func (st StackTrace) Frames() []runtime.Frame {
+	if len(st) == 0 {
+		return nil
+	}
+	out := make([]runtime.Frame, 0, len(st))
+	frames := runtime.CallersFrames(st)
+	for {
+		frame, more := frames.Next()
+		out = append(out, frame)
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+// one frame per two lines, function name first and then file:line
+func (st StackTrace) String() string {
+	b := strings.Builder{}
+	for _, frame := range st.Frames() {
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteString(`:`)
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// skip has the same meaning as GetCaller's skip
+func callers(skip, depth int) StackTrace {
+	pcs := make([]uintptr, depth)
+	n := runtime.Callers(skip+1, pcs)
+	return pcs[:n:n]
+}
+
+// skip has the same meaning as GetCaller's skip, returns 0 if not found
+func callerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+1, pcs[:]) < 1 {
+		return 0
+	}
+	return pcs[0]
+}
+
+func (o *Options) captureStack(def *Def) bool {
+	return o.stackDepth > 0 && (o.stackCapture == nil || o.stackCapture(def))
+}
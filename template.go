@@ -0,0 +1,91 @@
+package zerror
+
+import (
+	"fmt"
+	"strings"
+)
+
+// how to render placeholders whose keys are not found in data
+type MissingKeyPolicy int
+
+const (
+	// keep the placeholder as is, like `{user_id}`
+	MissingKeyKeep MissingKeyPolicy = iota
+	// replace the placeholder with empty string
+	MissingKeyEmpty
+	// replace the placeholder with `<missing>`
+	MissingKeyMark
+)
+
+// fill placeholders like `{user_id}` in tmpl with values in data,
+// `{{` and `}}` are escapes of `{` and `}`
+func fillTemplate(tmpl string, data Data, policy MissingKeyPolicy) string {
+	if !strings.ContainsAny(tmpl, `{}`) {
+		return tmpl
+	}
+	b := strings.Builder{}
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(tmpl) && tmpl[i+1] == c:
+			b.WriteByte(c)
+			i++
+		case c == '{':
+			end := strings.IndexByte(tmpl[i+1:], '}')
+			if end < 0 {
+				b.WriteString(tmpl[i:])
+				return b.String()
+			}
+			key := tmpl[i+1 : i+1+end]
+			if v, ok := data[key]; ok {
+				fmt.Fprint(&b, v)
+			} else {
+				switch policy {
+				case MissingKeyKeep:
+					b.WriteString(tmpl[i : i+end+2])
+				case MissingKeyMark:
+					b.WriteString(`<missing>`)
+				}
+			}
+			i += end + 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// report whether s has a well-formed placeholder like `{key}`,
+// escaped braces and unpaired braces like `expected {` are not placeholders
+func hasPlaceholder(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case (c == '{' || c == '}') && i+1 < len(s) && s[i+1] == c:
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end < 0 {
+				return false
+			}
+			if end > 0 && strings.IndexByte(s[i+1:i+1+end], '{') < 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the message of def localized with locales in the error's context,
+// placeholders are filled with data of the chain, sensitive data is redacted as rendering
+func (ze *Error) Message() string {
+	return ze.messageOf(ze.Def, true)
+}
+
+func (ze *Error) messageOf(def *Def, render bool) string {
+	tmpl := def.LocalizedMsg(LocalesFromContext(ze.Ctx)...)
+	if !hasPlaceholder(tmpl) {
+		return fillTemplate(tmpl, nil, Manager.missingKeyPolicy)
+	}
+	return fillTemplate(tmpl, ze.redactedData(render), Manager.missingKeyPolicy)
+}
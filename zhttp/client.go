@@ -0,0 +1,130 @@
+package zhttp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/EchoUtopia/zerror/v2"
+)
+
+const (
+	// the max bytes of response body read by DecodeResponse
+	maxErrorBodySize = 1 << 20
+	// the max bytes of message taken from body not rendered by zerror
+	maxErrorMsgSize = 512
+)
+
+// statuses of responses without error code are mapped to built-in defs
+var statusDefs = map[int]*zerror.Def{
+	http.StatusBadRequest:          zerror.BadRequest,
+	http.StatusUnauthorized:        zerror.Unauthenticated,
+	http.StatusForbidden:           zerror.Forbidden,
+	http.StatusNotFound:            zerror.NotFound,
+	http.StatusRequestTimeout:      zerror.DeadlineExceeded,
+	http.StatusConflict:            zerror.AlreadyExists,
+	int(zerror.StatusCancelled):    zerror.Canceled,
+	http.StatusInternalServerError: zerror.Internal,
+}
+
+// http.RoundTripper returning the error decoded by DecodeResponse for responses with status >= 400,
+// the response body is closed in that case, other responses like redirects are returned as is
+type Transport struct {
+	// http.DefaultTransport is used if nil
+	Base http.RoundTripper
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := DecodeResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// send req with client, responses with status >= 400 are returned as errors decoded by DecodeResponse,
+// the response body is closed in that case.
+// http.DefaultClient is used if client is nil
+func Do(client *http.Client, req *http.Request) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := DecodeResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// returns nil for responses with status < 400, otherwise decode the body rendered by zerror.StdResponse
+// or zerror.ProblemDetails to a remote *zerror.Error, see zerror.FromRemote.
+// if the body has no error code, the def is decided by the status.
+// the body can still be read after decoded
+func DecodeResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+	if err != nil {
+		return err
+	}
+	resp.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(b), resp.Body), Closer: resp.Body}
+
+	code, msg, details := decodeBody(resp.Header.Get(`Content-Type`), b)
+	if code == `` {
+		code = fmt.Sprintf(`http:%d`, resp.StatusCode)
+		if def, ok := statusDefs[resp.StatusCode]; ok {
+			code = def.Code
+		}
+	}
+	ze := zerror.FromRemote(code, zerror.Status(resp.StatusCode), msg)
+	if len(details) > 0 {
+		ze = ze.WithData(details)
+	}
+	return ze
+}
+
+func decodeBody(contentType string, b []byte) (code, msg string, details zerror.Data) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == zerror.ContentTypeProblem:
+		problem := &zerror.ProblemDetails{}
+		if err := json.Unmarshal(b, problem); err == nil && problem.Code != `` {
+			msg = problem.Detail
+			if msg == `` {
+				msg = problem.Title
+			}
+			return problem.Code, msg, problem.Extensions
+		}
+	case mediaType == `application/json`:
+		rsp := &zerror.StdResponse{}
+		if err := json.Unmarshal(b, rsp); err == nil && rsp.Code != `` {
+			return rsp.Code, rsp.Msg, rsp.Details
+		}
+	}
+	if len(b) > maxErrorMsgSize {
+		b = b[:maxErrorMsgSize]
+	}
+	return ``, strings.TrimSpace(string(b)), nil
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,120 @@
+package zhttp
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/EchoUtopia/zerror/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport(t *testing.T) {
+	m := zerror.Manager
+	defer func() { zerror.Manager = m }()
+	zerror.Init(zerror.SetDebugMode(true))
+
+	invalid := (&zerror.Def{Code: `remote:invalid`, Status: zerror.StatusBadRequest}).PublicKeys(`field`)
+	mux := http.NewServeMux()
+	mux.Handle(`/not-found`, HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.NotFound.WithMsg(`user 1`)
+	}))
+	mux.Handle(`/wrapped`, HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.NotFound.Wrapf(errors.New(`no rows`), `user 1`)
+	}))
+	mux.Handle(`/redirect`, http.RedirectHandler(`/ok`, http.StatusFound))
+	mux.Handle(`/invalid`, HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return invalid.New().WithKVs(`field`, `name`)
+	}))
+	mux.HandleFunc(`/unavailable`, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `service unavailable`, http.StatusServiceUnavailable)
+	})
+	mux.HandleFunc(`/ok`, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`ok`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := &http.Client{Transport: &Transport{}}
+	get := func(path string) (*http.Response, error) {
+		return client.Get(server.URL + path)
+	}
+
+	_, err := get(`/not-found`)
+	zerr := &zerror.Error{}
+	require.True(t, errors.As(err, &zerr))
+	require.True(t, zerror.NotFound.Cause(err))
+	require.True(t, zerr.Remote())
+	require.Equal(t, `zerror:not_found(user 1)`, zerr.Error())
+
+	_, err = get(`/wrapped`)
+	require.True(t, errors.As(err, &zerr))
+	require.Equal(t, `zerror:not_found(user 1) | no rows`, zerr.Error())
+
+	_, err = get(`/invalid`)
+	require.True(t, errors.As(err, &zerr))
+	require.Equal(t, invalid.Code, zerr.Code)
+	require.Equal(t, zerror.Data{`field`: `name`}, zerr.AllData())
+
+	_, err = get(`/unavailable`)
+	require.True(t, errors.As(err, &zerr))
+	require.Equal(t, `http:503`, zerr.Code)
+	require.Equal(t, zerror.StatusUnavailable, zerr.Status)
+	require.Equal(t, `http:503(service unavailable)`, zerr.Error())
+
+	for _, path := range []string{`/ok`, `/redirect`} {
+		resp, err := get(path)
+		require.Nil(t, err)
+		b, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Equal(t, `ok`, string(b))
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+`/not-found`, nil)
+	_, err = Do(nil, req)
+	require.True(t, zerror.NotFound.Cause(err))
+	req, _ = http.NewRequest(http.MethodGet, server.URL+`/redirect`, nil)
+	resp, err := Do(nil, req)
+	require.Nil(t, err)
+	resp.Body.Close()
+
+	zerror.Init(zerror.WithRender(zerror.ProblemRender(`https://errors.example.com/`), false))
+	_, err = get(`/not-found`)
+	require.True(t, errors.As(err, &zerr))
+	require.True(t, zerr.Def == zerror.NotFound)
+	require.Equal(t, `zerror:not_found(not found)`, zerr.Error())
+}
+
+func TestDecodeResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, `/`, nil), errors.New(`original error`))
+	resp := rec.Result()
+	err := DecodeResponse(resp)
+	require.True(t, zerror.Internal.Cause(err))
+	b, _ := io.ReadAll(resp.Body)
+	require.Equal(t, `{"code":"zerror:internal","msg":""}`, strings.TrimSpace(string(b)))
+
+	resp = &http.Response{
+		StatusCode: http.StatusForbidden,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(``)),
+	}
+	require.True(t, zerror.Forbidden.Cause(DecodeResponse(resp)))
+
+	for _, status := range []int{http.StatusSwitchingProtocols, http.StatusFound, http.StatusNotModified} {
+		resp.StatusCode = status
+		require.Nil(t, DecodeResponse(resp))
+	}
+
+	resp = &http.Response{
+		StatusCode: http.StatusBadGateway,
+		Header:     http.Header{`Content-Type`: {`application/json`}},
+		Body:       io.NopCloser(strings.NewReader(`{"error":"upstream db down"}`)),
+	}
+	require.Equal(t, `http:502({"error":"upstream db down"})`, DecodeResponse(resp).Error())
+	resp.Header.Set(`Content-Type`, zerror.ContentTypeProblem)
+	resp.Body = io.NopCloser(strings.NewReader(`{"title":"bad gateway"}`))
+	require.Equal(t, `http:502({"title":"bad gateway"})`, DecodeResponse(resp).Error())
+}
@@ -0,0 +1,146 @@
+// Package zhttp integrates zerror with net/http.
+package zhttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/EchoUtopia/zerror/v2"
+)
+
+// handler returning error, which is written by WriteError
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (f HandlerFunc) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := f(w, r); err != nil {
+		WriteError(w, r, err)
+	}
+}
+
+// convert err with zerror.From and write it with the render of zerror.Manager,
+// the status is the status of the error's response def, which is zerror.Internal if the error is caused by it,
+// or 500 if the def has no status.
+// the error is rendered with its own context, or the request context if it has none,
+// the request path is added as the instance of problem details,
+// and if the context has no locale, the locales of the request context or Accept-Language are added.
+// if w is or wraps *ResponseWriter, the error is recorded in it.
+// nothing is written if err is nil
+func WriteError(w http.ResponseWriter, r *http.Request, err error) {
+	if err == nil {
+		return
+	}
+	ze := zerror.From(err)
+	ctx := ze.Ctx
+	if ctx == nil {
+		ctx = r.Context()
+	}
+	if zerror.LocalesFromContext(ctx) == nil {
+		if locales := zerror.LocalesFromContext(r.Context()); locales != nil {
+			ctx = zerror.WithLocale(ctx, locales...)
+		} else {
+			ctx = zerror.WithAcceptLanguage(ctx, r.Header.Get(`Accept-Language`))
+		}
+	}
+	ctx = zerror.WithInstance(ctx, r.URL.Path)
+	rendered := ze.WithCtx(ctx).Render()
+	defer rendered.Release()
+
+	status := ze.ResponseDef().Status
+	if status == zerror.StatusInvalid {
+		status = zerror.StatusInternal
+	}
+	contentType := `application/json; charset=utf-8`
+	if ct, ok := rendered.Render.(zerror.ContentTyper); ok {
+		contentType = ct.ContentType()
+	}
+	if rw := findResponseWriter(w); rw != nil {
+		rw.err = err
+	}
+	w.Header().Set(`Content-Type`, contentType)
+	w.WriteHeader(int(status))
+	json.NewEncoder(w).Encode(rendered)
+}
+
+// middleware recovering panics in next, the panic is converted by zerror.Recover and written by WriteError,
+// http.ErrAbortHandler is panicked again to abort the response
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		defer func() {
+			if err == nil {
+				return
+			}
+			if errors.Is(err, http.ErrAbortHandler) {
+				panic(http.ErrAbortHandler)
+			}
+			WriteError(w, r, err)
+		}()
+		defer zerror.Recover(&err)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// records the status and the error written by WriteError, so middleware can read them after next is served
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	err    error
+}
+
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (rw *ResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *ResponseWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// the status written, 0 if nothing is written
+func (rw *ResponseWriter) Status() int {
+	return rw.status
+}
+
+// the error written by WriteError
+func (rw *ResponseWriter) Err() error {
+	return rw.err
+}
+
+// for http.ResponseController
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// middleware wrapping w with *ResponseWriter, and calling after with it when next is served
+func Record(after func(rw *ResponseWriter, r *http.Request)) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			after(rw, r)
+		})
+	}
+}
+
+func findResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	for {
+		switch t := w.(type) {
+		case *ResponseWriter:
+			return t
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = t.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package zhttp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/EchoUtopia/zerror/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	zerror.Init()
+	m.Run()
+}
+
+func serve(h http.Handler, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, `/users/1`, nil)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) map[string]interface{} {
+	out := map[string]interface{}{}
+	require.Nil(t, json.Unmarshal(rec.Body.Bytes(), &out))
+	return out
+}
+
+func TestHandlerFunc(t *testing.T) {
+	rec := serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.NotFound.New()
+	}), nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, `application/json; charset=utf-8`, rec.Header().Get(`Content-Type`))
+	require.Equal(t, zerror.NotFound.Code, decode(t, rec)[`code`])
+
+	rec = serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.BadRequest.Wrap(zerror.Internal.New())
+	}), nil)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, zerror.Internal.Code, decode(t, rec)[`code`])
+
+	rec = serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		w.Write([]byte(`ok`))
+		return nil
+	}), nil)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, `ok`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	WriteError(rec, httptest.NewRequest(http.MethodGet, `/`, nil), nil)
+	require.Zero(t, rec.Body.Len())
+
+	m := zerror.Manager
+	defer func() { zerror.Manager = m }()
+	zerror.Init(zerror.WithMultiPolicy(zerror.FirstError))
+	rec = serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.NotFound.Wrap(zerror.Join(zerror.BadRequest.New(), zerror.Internal.New()))
+	}), nil)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestWriteErrorWithManagerOptions(t *testing.T) {
+	m := zerror.Manager
+	defer func() { zerror.Manager = m }()
+	catalog := zerror.NewCatalog()
+	catalog.Register(`zh`, zerror.NotFound.Code, zerror.CatalogEntry{Msg: `未找到`})
+	zerror.Init(
+		zerror.WithRender(zerror.ProblemRender(`https://errors.example.com/`), false),
+		zerror.WithCatalog(catalog, `en`),
+	)
+
+	rec := serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return fmt.Errorf(`query: %w`, zerror.NotFound.New())
+	}), http.Header{`Accept-Language`: {`zh-CN,en;q=0.5`}})
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Equal(t, zerror.ContentTypeProblem, rec.Header().Get(`Content-Type`))
+	body := decode(t, rec)
+	require.Equal(t, `https://errors.example.com/zerror:not_found`, body[`type`])
+	require.Equal(t, `未找到`, body[`title`])
+	require.Equal(t, `/users/1`, body[`instance`])
+
+	rec = serve(HandlerFunc(func(w http.ResponseWriter, r *http.Request) error {
+		return zerror.NotFound.New().WithCtx(zerror.WithLocale(context.Background(), `en`))
+	}), http.Header{`Accept-Language`: {`zh-CN`}})
+	body = decode(t, rec)
+	require.Equal(t, `not found`, body[`title`])
+	require.Equal(t, `/users/1`, body[`instance`])
+}
+
+func TestRecover(t *testing.T) {
+	rec := serve(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(`boom`)
+	})), nil)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, zerror.Internal.Code, decode(t, rec)[`code`])
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		serve(Recover(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})), nil)
+	})
+}
+
+type wrappedWriter struct {
+	http.ResponseWriter
+}
+
+func (w *wrappedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestRecord(t *testing.T) {
+	var recorded *ResponseWriter
+	original := errors.New(`original error`)
+	handler := Record(func(rw *ResponseWriter, r *http.Request) {
+		recorded = rw
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		WriteError(&wrappedWriter{w}, r, original)
+	}))
+	rec := serve(handler, nil)
+	require.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.Equal(t, http.StatusInternalServerError, recorded.Status())
+	require.Equal(t, original, recorded.Err())
+}